@@ -0,0 +1,69 @@
+package audit
+
+import (
+	"encoding/json"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Decisions recorded in the audit log
+const (
+	Evicted  = "evicted"
+	Skipped  = "skipped"
+	Deferred = "deferred"
+	Failed   = "failed"
+	// Placed records where the replacement of an evicted Pod was placed
+	Placed = "placed"
+)
+
+// Record is one decision of the rescheduler, written as a single JSON line
+type Record struct {
+	CycleID    string            `json:"cycleId"`
+	Timestamp  time.Time         `json:"timestamp"`
+	Decision   string            `json:"decision"`
+	Strategy   string            `json:"strategy"`
+	Group      string            `json:"group,omitempty"`
+	Namespace  string            `json:"namespace,omitempty"`
+	Pod        string            `json:"pod,omitempty"`
+	PodUID     string            `json:"podUid,omitempty"`
+	SourceNode string            `json:"sourceNode,omitempty"`
+	TargetNode string            `json:"targetNode,omitempty"`
+	Reason     string            `json:"reason,omitempty"`
+	Inputs     map[string]string `json:"inputs,omitempty"`
+	Result     string            `json:"result,omitempty"`
+}
+
+// Log appends the records to a size rotated file
+type Log struct {
+	writer *rotatingFile
+}
+
+// NewLog opens the audit log file, it is rotated when it grows over maxSize bytes and at most maxBackups rotated files are kept
+func NewLog(path string, maxSize int64, maxBackups int) (*Log, error) {
+	writer, err := openRotatingFile(path, maxSize, maxBackups)
+	if err != nil {
+		return nil, err
+	}
+	return &Log{writer: writer}, nil
+}
+
+// Record appends the record to the audit log, the timestamp is set if it is missing
+func (l *Log) Record(record Record) {
+	if record.Timestamp.IsZero() {
+		record.Timestamp = time.Now()
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		log.Errorf("Failed to marshal audit record: %s", err.Error())
+		return
+	}
+	if err := l.writer.writeLine(line); err != nil {
+		log.Errorf("Failed to write audit log: %s", err.Error())
+	}
+}
+
+// Close closes the audit log file
+func (l *Log) Close() error {
+	return l.writer.close()
+}
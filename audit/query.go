@@ -0,0 +1,84 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Filter selects audit records, empty fields match everything
+type Filter struct {
+	// Pod matches the Pod UID, name or namespace/name
+	Pod   string
+	Group string
+	// Node matches either the source or the target node
+	Node  string
+	Since time.Time
+	Until time.Time
+}
+
+// Matches tells whether the record is selected by the filter
+func (f *Filter) Matches(record *Record) bool {
+	if len(f.Pod) > 0 && f.Pod != record.PodUID && f.Pod != record.Pod && f.Pod != record.Namespace+"/"+record.Pod {
+		return false
+	}
+	if len(f.Group) > 0 && f.Group != record.Group {
+		return false
+	}
+	if len(f.Node) > 0 && f.Node != record.SourceNode && f.Node != record.TargetNode {
+		return false
+	}
+	if !f.Since.IsZero() && record.Timestamp.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && record.Timestamp.After(f.Until) {
+		return false
+	}
+	return true
+}
+
+// Query writes the matching records of the audit log and its rotated files to out, oldest first
+func Query(path string, maxBackups int, filter *Filter, out io.Writer) error {
+	for i := maxBackups; i >= 0; i-- {
+		file := path
+		if i > 0 {
+			file = BackupPath(path, i)
+		}
+		if err := queryFile(file, filter, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func queryFile(path string, filter *Filter, out io.Writer) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			log.Warnf("Skipping invalid audit record at %s:%d: %s", path, line, err.Error())
+			continue
+		}
+		if filter.Matches(&record) {
+			if _, err := out.Write(append(scanner.Bytes(), '\n')); err != nil {
+				return err
+			}
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,114 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testTime = time.Date(2018, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func TestFilterMatches(t *testing.T) {
+	record := Record{
+		Timestamp:  testTime,
+		Decision:   Evicted,
+		Group:      "default/web",
+		Namespace:  "default",
+		Pod:        "web-0",
+		PodUID:     "uid-0",
+		SourceNode: "n0",
+		TargetNode: "n1",
+	}
+	cases := []struct {
+		name    string
+		filter  Filter
+		matches bool
+	}{
+		{name: "empty filter", matches: true},
+		{name: "pod name", filter: Filter{Pod: "web-0"}, matches: true},
+		{name: "pod namespace and name", filter: Filter{Pod: "default/web-0"}, matches: true},
+		{name: "pod UID", filter: Filter{Pod: "uid-0"}, matches: true},
+		{name: "other pod", filter: Filter{Pod: "web-1"}},
+		{name: "pod of another namespace", filter: Filter{Pod: "other/web-0"}},
+		{name: "group", filter: Filter{Group: "default/web"}, matches: true},
+		{name: "other group", filter: Filter{Group: "default/db"}},
+		{name: "source node", filter: Filter{Node: "n0"}, matches: true},
+		{name: "target node", filter: Filter{Node: "n1"}, matches: true},
+		{name: "other node", filter: Filter{Node: "n2"}},
+		{name: "within the time range", filter: Filter{Since: testTime.Add(-time.Hour), Until: testTime.Add(time.Hour)}, matches: true},
+		{name: "time range bounds are inclusive", filter: Filter{Since: testTime, Until: testTime}, matches: true},
+		{name: "before the time range", filter: Filter{Since: testTime.Add(time.Second)}},
+		{name: "after the time range", filter: Filter{Until: testTime.Add(-time.Second)}},
+		{name: "every field has to match", filter: Filter{Pod: "web-0", Group: "default/web", Node: "n2"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if matches := c.filter.Matches(&record); matches != c.matches {
+				t.Errorf("expected match %v, got %v", c.matches, matches)
+			}
+		})
+	}
+}
+
+func TestQueryReadsTheBackupsOldestFirst(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+	record := func(i int, pod string) Record {
+		return Record{CycleID: "cycle", Timestamp: testTime.Add(time.Duration(i) * time.Minute), Decision: Skipped, Group: "default/" + pod[:1], Namespace: "default", Pod: pod}
+	}
+	// the records have the same length, every file keeps two of them
+	line, _ := json.Marshal(record(0, "a-0"))
+	l, err := NewLog(path, int64(2*len(line)+2), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, pod := range []string{"a-0", "b-0", "a-1", "b-1", "a-2", "b-2", "a-3"} {
+		l.Record(record(i, pod))
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(BackupPath(path, 3)); !os.IsNotExist(err) {
+		t.Errorf("expected at most 2 backups, got: %v", err)
+	}
+
+	cases := []struct {
+		name       string
+		filter     Filter
+		maxBackups int
+		want       string
+	}{
+		{name: "every kept record", maxBackups: 2, want: "a-1,b-1,a-2,b-2,a-3"},
+		{name: "the current file only", want: "a-3"},
+		{name: "group across the files", filter: Filter{Group: "default/a"}, maxBackups: 5, want: "a-1,a-2,a-3"},
+		{name: "time range across the files", filter: Filter{Since: testTime.Add(3 * time.Minute), Until: testTime.Add(5 * time.Minute)}, maxBackups: 2, want: "b-1,a-2,b-2"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := Query(path, c.maxBackups, &c.filter, &out); err != nil {
+				t.Fatal(err)
+			}
+			var pods []string
+			for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+				var record Record
+				if err := json.Unmarshal([]byte(line), &record); err != nil {
+					t.Fatalf("invalid record %q: %v", line, err)
+				}
+				pods = append(pods, record.Pod)
+			}
+			if got := strings.Join(pods, ","); got != c.want {
+				t.Errorf("unexpected records: %s, want %s", got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package audit
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// rotatingFile renames the file to path.1 when it would grow over the maximum size, path.1 to path.2 and so on.
+// Without backups the file is truncated instead
+type rotatingFile struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+	// the size the file is rotated at, it is moved further when the rotation fails
+	rotateAt int64
+	mutex    sync.Mutex
+}
+
+func openRotatingFile(path string, maxSize int64, maxBackups int) (*rotatingFile, error) {
+	f := &rotatingFile{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log %s: %s", f.path, err.Error())
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to open audit log %s: %s", f.path, err.Error())
+	}
+	f.file = file
+	f.size = info.Size()
+	f.rotateAt = f.maxSize
+	return nil
+}
+
+// Write the line, the file is rotated first if the line does not fit into it.
+// A failed rotation is returned, but the line is written to the current file and the rotation is tried again
+// only when the file grows by the maximum size again, so the error is not reported for every line
+func (f *rotatingFile) writeLine(line []byte) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	var rotateErr error
+	if f.maxSize > 0 && f.size > 0 && f.size+int64(len(line))+1 > f.rotateAt {
+		if err := f.rotate(); err != nil {
+			f.rotateAt = f.size + f.maxSize
+			rotateErr = fmt.Errorf("failed to rotate audit log %s, writing on to the current file: %s", f.path, err.Error())
+		}
+	}
+	n, err := f.file.Write(append(line, '\n'))
+	f.size += int64(n)
+	if err != nil {
+		return err
+	}
+	return rotateErr
+}
+
+// Rotate the file, the current file is kept open until the new one is opened
+func (f *rotatingFile) rotate() error {
+	if f.maxBackups == 0 {
+		if err := f.file.Truncate(0); err != nil {
+			return err
+		}
+		f.size, f.rotateAt = 0, f.maxSize
+		return nil
+	}
+	if err := os.Remove(BackupPath(f.path, f.maxBackups)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for i := f.maxBackups - 1; i > 0; i-- {
+		if err := os.Rename(BackupPath(f.path, i), BackupPath(f.path, i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Rename(f.path, BackupPath(f.path, 1)); err != nil {
+		return err
+	}
+	current := f.file
+	if err := f.open(); err != nil {
+		// the lines are written to the renamed file until the next rotation
+		return err
+	}
+	current.Close()
+	return nil
+}
+
+func (f *rotatingFile) close() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.file.Close()
+}
+
+// BackupPath is the path of the n-th rotated audit log file, path.1 is the most recent one
+func BackupPath(path string, n int) string {
+	return fmt.Sprintf("%s.%d", path, n)
+}
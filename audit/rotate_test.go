@@ -0,0 +1,110 @@
+package audit
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readLines(t *testing.T, path string) string {
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "missing"
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Join(strings.Fields(string(content)), ",")
+}
+
+func testFile(t *testing.T, maxSize int64, maxBackups int) (*rotatingFile, string) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "audit.log")
+	f, err := openRotatingFile(path, maxSize, maxBackups)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.close() })
+	return f, path
+}
+
+func writeLines(t *testing.T, f *rotatingFile, lines ...string) {
+	for _, line := range lines {
+		if err := f.writeLine([]byte(line)); err != nil {
+			t.Fatalf("failed to write %s: %v", line, err)
+		}
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	cases := []struct {
+		name       string
+		maxSize    int64
+		maxBackups int
+		want       map[int]string
+	}{
+		{
+			name:       "the oldest backups are removed",
+			maxSize:    6,
+			maxBackups: 2,
+			want:       map[int]string{0: "l6,l7", 1: "l4,l5", 2: "l2,l3", 3: "missing"},
+		},
+		{
+			name:       "without backups the file is truncated",
+			maxSize:    6,
+			maxBackups: 0,
+			want:       map[int]string{0: "l6,l7", 1: "missing"},
+		},
+		{
+			name:       "without a maximum size the file is not rotated",
+			maxBackups: 2,
+			want:       map[int]string{0: "l0,l1,l2,l3,l4,l5,l6,l7", 1: "missing"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			f, path := testFile(t, c.maxSize, c.maxBackups)
+			writeLines(t, f, "l0", "l1", "l2", "l3", "l4", "l5", "l6", "l7")
+			for i, want := range c.want {
+				file := path
+				if i > 0 {
+					file = BackupPath(path, i)
+				}
+				if got := readLines(t, file); got != want {
+					t.Errorf("unexpected lines of %s: %s, want %s", filepath.Base(file), got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRotatingFileKeepsWritingWhenTheRotationFails(t *testing.T) {
+	f, path := testFile(t, 6, 1)
+	// the lines take 3 bytes, the oldest backup cannot be removed
+	if err := os.MkdirAll(filepath.Join(BackupPath(path, 1), "blocked"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeLines(t, f, "l0", "l1")
+	if err := f.writeLine([]byte("l2")); err == nil || !strings.Contains(err.Error(), "failed to rotate") {
+		t.Errorf("expected the failed rotation to be returned, got: %v", err)
+	}
+	// the rotation is not tried again for the next line
+	writeLines(t, f, "l3")
+	if got := readLines(t, path); got != "l0,l1,l2,l3" {
+		t.Errorf("expected every line in the current file, got: %s", got)
+	}
+
+	if err := os.RemoveAll(BackupPath(path, 1)); err != nil {
+		t.Fatal(err)
+	}
+	writeLines(t, f, "l4")
+	if current, backup := readLines(t, path), readLines(t, BackupPath(path, 1)); current != "l4" || backup != "l0,l1,l2,l3" {
+		t.Errorf("expected the file to be rotated once it grew by the maximum size, got: %s and %s", current, backup)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hortonworks/pod-rescheduler/audit"
+)
+
+// pod-rescheduler audit query [flags]: print the matching records of the audit log
+func auditCommand(args []string) int {
+	if len(args) == 0 || args[0] != "query" {
+		fmt.Fprintln(os.Stderr, "Usage: pod-rescheduler audit query [flags]")
+		return 2
+	}
+	flags := flag.NewFlagSet("audit query", flag.ContinueOnError)
+	file := flags.String("audit-log-file", "", "Path of the audit log file")
+	maxBackups := flags.Int("audit-log-max-backups", 5, "How many rotated audit log files are searched")
+	pod := flags.String("pod", "", "Filter by Pod UID, name or namespace/name")
+	group := flags.String("group", "", "Filter by Pod group (namespace/name)")
+	node := flags.String("node", "", "Filter by source or target node")
+	since := flags.String("since", "", "Only records after this time, RFC3339 or a duration ago (e.g. 48h)")
+	until := flags.String("until", "", "Only records before this time, RFC3339 or a duration ago (e.g. 24h)")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if len(*file) == 0 {
+		fmt.Fprintln(os.Stderr, "--audit-log-file is required")
+		return 2
+	}
+	filter := &audit.Filter{Pod: *pod, Group: *group, Node: *node}
+	var err error
+	if filter.Since, err = parseTimeFlag(*since); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid --since: %s\n", err.Error())
+		return 2
+	}
+	if filter.Until, err = parseTimeFlag(*until); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid --until: %s\n", err.Error())
+		return 2
+	}
+	if err := audit.Query(*file, *maxBackups, filter, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "Audit query failed: %s\n", err.Error())
+		return 1
+	}
+	return 0
+}
+
+// An absolute RFC3339 time or a duration before now, empty means no limit
+func parseTimeFlag(value string) (time.Time, error) {
+	if len(value) == 0 {
+		return time.Time{}, nil
+	}
+	if ago, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-ago), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
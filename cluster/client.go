@@ -0,0 +1,189 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// Client is the part of the Kubernetes API used by the rescheduler.
+// It is implemented by the API server and by an in-memory cluster for the simulations.
+// The calls are aborted when the context is cancelled or its deadline is exceeded
+type Client interface {
+	ListNodes(ctx context.Context) ([]corev1.Node, error)
+	// Changes of the nodes until the context is cancelled or the API server closes the watch
+	WatchNodes(ctx context.Context) (watch.Interface, error)
+	// Pods of the namespace running on the node, every namespace is listed when the namespace is empty
+	// and the Pods of every node when the node is empty
+	ListPods(ctx context.Context, namespace, node string) ([]corev1.Pod, error)
+	GetPod(ctx context.Context, namespace, name string) (*corev1.Pod, error)
+	DeletePod(ctx context.Context, namespace, name string) error
+	GetNode(ctx context.Context, name string) (*corev1.Node, error)
+	// Replace the node, it fails with a conflict if the node was changed since it was read
+	UpdateNode(ctx context.Context, node *corev1.Node) error
+	// Cordon or uncordon the node
+	SetUnschedulable(ctx context.Context, node string, unschedulable bool) error
+	ServerVersion(ctx context.Context) (string, error)
+}
+
+// apiClient builds the requests of the typed clients itself, because only the requests accept a context
+type apiClient struct {
+	clientSet kubernetes.Interface
+	// Pods per page of the Pod lists, 0 lists them in a single page
+	pageSize int64
+}
+
+// podPage is a page of a Pod list, the vendored API types do not know the continue token of the list metadata
+type podPage struct {
+	Metadata struct {
+		Continue string `json:"continue,omitempty"`
+	} `json:"metadata"`
+	Items []corev1.Pod `json:"items"`
+}
+
+// NewClient returns a Client which calls the API server and lists the Pods in pages of the given size
+func NewClient(clientSet kubernetes.Interface, pageSize int64) Client {
+	return &apiClient{clientSet: clientSet, pageSize: pageSize}
+}
+
+func (c *apiClient) ListNodes(ctx context.Context) ([]corev1.Node, error) {
+	nodes := &corev1.NodeList{}
+	err := c.clientSet.CoreV1().RESTClient().Get().
+		Resource("nodes").
+		Context(ctx).
+		Do().
+		Into(nodes)
+	if err != nil {
+		return nil, err
+	}
+	return nodes.Items, nil
+}
+
+func (c *apiClient) WatchNodes(ctx context.Context) (watch.Interface, error) {
+	return c.clientSet.CoreV1().RESTClient().Get().
+		Resource("nodes").
+		VersionedParams(&metav1.ListOptions{Watch: true}, scheme.ParameterCodec).
+		Context(ctx).
+		Watch()
+}
+
+// The Pods are listed in pages, a server which does not support the paging returns all of them in the first page
+func (c *apiClient) ListPods(ctx context.Context, namespace, node string) ([]corev1.Pod, error) {
+	options := metav1.ListOptions{}
+	if len(node) > 0 {
+		options.FieldSelector = fields.SelectorFromSet(fields.Set{"spec.nodeName": node}).String()
+	}
+	var pods []corev1.Pod
+	token := ""
+	for {
+		request := c.clientSet.CoreV1().RESTClient().Get().
+			Namespace(namespace).
+			Resource("pods").
+			VersionedParams(&options, scheme.ParameterCodec)
+		if c.pageSize > 0 {
+			request = request.Param("limit", strconv.FormatInt(c.pageSize, 10))
+		}
+		if len(token) > 0 {
+			request = request.Param("continue", token)
+		}
+		body, err := request.Context(ctx).Do().Raw()
+		if err != nil {
+			return nil, err
+		}
+		page := &podPage{}
+		if err := json.Unmarshal(body, page); err != nil {
+			return nil, err
+		}
+		pods = append(pods, page.Items...)
+		if token = page.Metadata.Continue; len(token) == 0 {
+			return pods, nil
+		}
+	}
+}
+
+func (c *apiClient) GetPod(ctx context.Context, namespace, name string) (*corev1.Pod, error) {
+	pod := &corev1.Pod{}
+	err := c.clientSet.CoreV1().RESTClient().Get().
+		Namespace(namespace).
+		Resource("pods").
+		Name(name).
+		Context(ctx).
+		Do().
+		Into(pod)
+	if err != nil {
+		return nil, err
+	}
+	return pod, nil
+}
+
+func (c *apiClient) DeletePod(ctx context.Context, namespace, name string) error {
+	return c.clientSet.CoreV1().RESTClient().Delete().
+		Namespace(namespace).
+		Resource("pods").
+		Name(name).
+		Body(&metav1.DeleteOptions{}).
+		Context(ctx).
+		Do().
+		Error()
+}
+
+func (c *apiClient) GetNode(ctx context.Context, name string) (*corev1.Node, error) {
+	node := &corev1.Node{}
+	err := c.clientSet.CoreV1().RESTClient().Get().
+		Resource("nodes").
+		Name(name).
+		Context(ctx).
+		Do().
+		Into(node)
+	if err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+func (c *apiClient) UpdateNode(ctx context.Context, node *corev1.Node) error {
+	return c.clientSet.CoreV1().RESTClient().Put().
+		Resource("nodes").
+		Name(node.Name).
+		Body(node).
+		Context(ctx).
+		Do().
+		Error()
+}
+
+func (c *apiClient) SetUnschedulable(ctx context.Context, node string, unschedulable bool) error {
+	patch := fmt.Sprintf(`{"spec":{"unschedulable":%t}}`, unschedulable)
+	return c.clientSet.CoreV1().RESTClient().Patch(types.StrategicMergePatchType).
+		Resource("nodes").
+		Name(node).
+		Body([]byte(patch)).
+		Context(ctx).
+		Do().
+		Error()
+}
+
+func (c *apiClient) ServerVersion(ctx context.Context) (string, error) {
+	body, err := c.clientSet.Discovery().RESTClient().Get().
+		AbsPath("/version").
+		Context(ctx).
+		Do().
+		Raw()
+	if err != nil {
+		return "", err
+	}
+	var info version.Info
+	if err := json.Unmarshal(body, &info); err != nil {
+		return "", err
+	}
+	return info.GitVersion, nil
+}
@@ -0,0 +1,161 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+var (
+	podResource  = schema.GroupResource{Resource: "pods"}
+	nodeResource = schema.GroupResource{Resource: "nodes"}
+)
+
+// Memory is an in-memory cluster initialized from a snapshot, the deleted Pods are replaced by the simulated scheduler
+type Memory struct {
+	mutex   sync.Mutex
+	nodes   []corev1.Node
+	pods    []corev1.Pod
+	evicted []corev1.Pod
+	created int
+}
+
+// NewMemory returns an in-memory cluster with the Nodes and Pods of the snapshot
+func NewMemory(s *Snapshot) *Memory {
+	m := &Memory{
+		nodes: make([]corev1.Node, len(s.Nodes)),
+		pods:  make([]corev1.Pod, len(s.Pods)),
+	}
+	copy(m.nodes, s.Nodes)
+	copy(m.pods, s.Pods)
+	return m
+}
+
+func (m *Memory) ListNodes(ctx context.Context) ([]corev1.Node, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	result := make([]corev1.Node, len(m.nodes))
+	copy(result, m.nodes)
+	return result, nil
+}
+
+// WatchNodes returns a watch without changes, the simulations run their cycles one after the other
+func (m *Memory) WatchNodes(ctx context.Context) (watch.Interface, error) {
+	return watch.NewFake(), nil
+}
+
+func (m *Memory) ListPods(ctx context.Context, namespace, node string) ([]corev1.Pod, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	var result []corev1.Pod
+	for _, pod := range m.pods {
+		if (len(namespace) == 0 || pod.Namespace == namespace) && (len(node) == 0 || pod.Spec.NodeName == node) {
+			result = append(result, pod)
+		}
+	}
+	return result, nil
+}
+
+func (m *Memory) GetPod(ctx context.Context, namespace, name string) (*corev1.Pod, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if i := m.indexOf(namespace, name); i >= 0 {
+		pod := m.pods[i]
+		return &pod, nil
+	}
+	return nil, errors.NewNotFound(podResource, name)
+}
+
+func (m *Memory) DeletePod(ctx context.Context, namespace, name string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	i := m.indexOf(namespace, name)
+	if i < 0 {
+		return errors.NewNotFound(podResource, name)
+	}
+	m.evicted = append(m.evicted, m.pods[i])
+	m.pods = append(m.pods[:i], m.pods[i+1:]...)
+	return nil
+}
+
+func (m *Memory) GetNode(ctx context.Context, name string) (*corev1.Node, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for i := range m.nodes {
+		if m.nodes[i].Name == name {
+			node := m.nodes[i]
+			return &node, nil
+		}
+	}
+	return nil, errors.NewNotFound(nodeResource, name)
+}
+
+// UpdateNode replaces the node, the in-memory cluster has no conflicts
+func (m *Memory) UpdateNode(ctx context.Context, node *corev1.Node) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for i := range m.nodes {
+		if m.nodes[i].Name == node.Name {
+			m.nodes[i] = *node
+			return nil
+		}
+	}
+	return errors.NewNotFound(nodeResource, node.Name)
+}
+
+func (m *Memory) SetUnschedulable(ctx context.Context, node string, unschedulable bool) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for i := range m.nodes {
+		if m.nodes[i].Name == node {
+			m.nodes[i].Spec.Unschedulable = unschedulable
+			return nil
+		}
+	}
+	return errors.NewNotFound(nodeResource, node)
+}
+
+func (m *Memory) ServerVersion(ctx context.Context) (string, error) {
+	return "in-memory", nil
+}
+
+// Pods returns the Pods of the cluster
+func (m *Memory) Pods() []corev1.Pod {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	result := make([]corev1.Pod, len(m.pods))
+	copy(result, m.pods)
+	return result
+}
+
+func (m *Memory) indexOf(namespace, name string) int {
+	for i, pod := range m.pods {
+		if pod.Namespace == namespace && pod.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// The name of the replacement Pod, StatefulSet Pods keep their names
+func (m *Memory) replacementName(pod *corev1.Pod) string {
+	if len(pod.GenerateName) == 0 || isStatefulSetPod(pod) {
+		return pod.Name
+	}
+	m.created++
+	return fmt.Sprintf("%ssim%02d", pod.GenerateName, m.created)
+}
+
+func isStatefulSetPod(pod *corev1.Pod) bool {
+	for _, owner := range pod.OwnerReferences {
+		if owner.Kind == "StatefulSet" {
+			return true
+		}
+	}
+	return false
+}
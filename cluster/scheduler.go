@@ -0,0 +1,95 @@
+package cluster
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Placement is a deleted Pod and its replacement created by the controller and placed by the scheduler
+type Placement struct {
+	Evicted     corev1.Pod
+	Replacement *corev1.Pod
+}
+
+// Schedule replaces the Pods deleted since the last call as their controllers would, the replacements are placed
+// by a simple scheduler: the schedulable, ready node without pressure with the fewest Pods of the same controller,
+// then with the fewest Pods, then the first by name. The replacements are Running and Ready at once. Pods without a controller are not replaced
+func (m *Memory) Schedule(now time.Time) []Placement {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	var placements []Placement
+	for _, evicted := range m.evicted {
+		placement := Placement{Evicted: evicted}
+		if owner := metav1.GetControllerOf(&evicted); owner != nil {
+			if node := m.selectNode(owner.UID); node != nil {
+				replacement := m.replacement(&evicted, node.Name, now)
+				m.pods = append(m.pods, *replacement)
+				placement.Replacement = replacement
+			}
+		}
+		placements = append(placements, placement)
+	}
+	m.evicted = nil
+	return placements
+}
+
+func (m *Memory) selectNode(controller types.UID) *corev1.Node {
+	var selected *corev1.Node
+	var selectedSiblings, selectedPods int
+	for i := range m.nodes {
+		node := &m.nodes[i]
+		if node.Spec.Unschedulable || len(node.Spec.Taints) > 0 || !fit(node) {
+			continue
+		}
+		siblings, pods := 0, 0
+		for _, pod := range m.pods {
+			if pod.Spec.NodeName != node.Name {
+				continue
+			}
+			pods++
+			if owner := metav1.GetControllerOf(&pod); owner != nil && owner.UID == controller {
+				siblings++
+			}
+		}
+		if selected == nil || siblings < selectedSiblings ||
+			(siblings == selectedSiblings && (pods < selectedPods || (pods == selectedPods && node.Name < selected.Name))) {
+			selected, selectedSiblings, selectedPods = node, siblings, pods
+		}
+	}
+	return selected
+}
+
+func (m *Memory) replacement(evicted *corev1.Pod, node string, now time.Time) *corev1.Pod {
+	pod := evicted.DeepCopy()
+	pod.Name = m.replacementName(evicted)
+	pod.UID = types.UID(pod.Namespace + "/" + pod.Name + "/" + now.Format(time.RFC3339Nano))
+	pod.CreationTimestamp = metav1.NewTime(now)
+	pod.Spec.NodeName = node
+	pod.Status.Phase = corev1.PodRunning
+	pod.Status.StartTime = &pod.CreationTimestamp
+	for i := range pod.Status.ContainerStatuses {
+		pod.Status.ContainerStatuses[i].Ready = true
+		pod.Status.ContainerStatuses[i].RestartCount = 0
+	}
+	return pod
+}
+
+// The node is Ready and it does not have any pressure condition, the node conditions missing from the snapshot are ignored
+func fit(node *corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		switch condition.Type {
+		case corev1.NodeReady:
+			if condition.Status != corev1.ConditionTrue {
+				return false
+			}
+		case corev1.NodeMemoryPressure, corev1.NodeDiskPressure, "PIDPressure":
+			if condition.Status == corev1.ConditionTrue {
+				return false
+			}
+		}
+	}
+	return true
+}
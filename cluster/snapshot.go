@@ -0,0 +1,96 @@
+package cluster
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	appsv1beta1 "k8s.io/api/apps/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Snapshot is the state of the cluster which the rescheduler decisions depend on
+type Snapshot struct {
+	CapturedAt             metav1.Time                         `json:"capturedAt"`
+	Namespace              string                              `json:"namespace,omitempty"`
+	ScopedPodsOnly         bool                                `json:"scopedPodsOnly,omitempty"`
+	Nodes                  []corev1.Node                       `json:"nodes"`
+	Pods                   []corev1.Pod                        `json:"pods"`
+	Deployments            []appsv1beta1.Deployment            `json:"deployments"`
+	StatefulSets           []appsv1beta1.StatefulSet           `json:"statefulSets"`
+	ReplicaSets            []extensionsv1beta1.ReplicaSet      `json:"replicaSets"`
+	ReplicationControllers []corev1.ReplicationController      `json:"replicationControllers"`
+	PodDisruptionBudgets   []policyv1beta1.PodDisruptionBudget `json:"podDisruptionBudgets"`
+}
+
+// Capture lists the Nodes and the objects of the namespace, every namespace is captured when it is empty.
+// The Pods of every namespace are captured, because all of them take room on the nodes. When they cannot be listed
+// for lack of permission, e.g. with a namespaced Role, only the Pods of the namespace are captured
+func Capture(clientSet kubernetes.Interface, namespace string) (*Snapshot, error) {
+	s := &Snapshot{CapturedAt: metav1.NewTime(time.Now()), Namespace: namespace}
+	options := metav1.ListOptions{}
+	nodes, err := clientSet.CoreV1().Nodes().List(options)
+	if err != nil {
+		return nil, err
+	}
+	s.Nodes = nodes.Items
+	pods, err := clientSet.CoreV1().Pods(metav1.NamespaceAll).List(options)
+	if apierrors.IsForbidden(err) && len(namespace) > 0 {
+		s.ScopedPodsOnly = true
+		pods, err = clientSet.CoreV1().Pods(namespace).List(options)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.Pods = pods.Items
+	deployments, err := clientSet.AppsV1beta1().Deployments(namespace).List(options)
+	if err != nil {
+		return nil, err
+	}
+	s.Deployments = deployments.Items
+	statefulSets, err := clientSet.AppsV1beta1().StatefulSets(namespace).List(options)
+	if err != nil {
+		return nil, err
+	}
+	s.StatefulSets = statefulSets.Items
+	replicaSets, err := clientSet.ExtensionsV1beta1().ReplicaSets(namespace).List(options)
+	if err != nil {
+		return nil, err
+	}
+	s.ReplicaSets = replicaSets.Items
+	replicationControllers, err := clientSet.CoreV1().ReplicationControllers(namespace).List(options)
+	if err != nil {
+		return nil, err
+	}
+	s.ReplicationControllers = replicationControllers.Items
+	pdbs, err := clientSet.PolicyV1beta1().PodDisruptionBudgets(namespace).List(options)
+	if err != nil {
+		return nil, err
+	}
+	s.PodDisruptionBudgets = pdbs.Items
+	return s, nil
+}
+
+// Write the snapshot as JSON
+func (s *Snapshot) Write(out io.Writer) error {
+	encoded, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(append(encoded, '\n'))
+	return err
+}
+
+// ReadSnapshot reads a snapshot written by Write
+func ReadSnapshot(in io.Reader) (*Snapshot, error) {
+	s := &Snapshot{}
+	if err := json.NewDecoder(in).Decode(s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
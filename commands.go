@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/ghodss/yaml"
+	"github.com/hortonworks/pod-rescheduler/notify"
+	"github.com/hortonworks/pod-rescheduler/pkg/engine"
+	"github.com/hortonworks/pod-rescheduler/policy"
+	utils "github.com/hortonworks/pod-rescheduler/utils"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Output formats of the plan and explain commands
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+// A row of the plan and explain output
+type planEntry struct {
+	Action    string `json:"action"`
+	Strategy  string `json:"strategy"`
+	Group     string `json:"group"`
+	Namespace string `json:"namespace,omitempty"`
+	Pod       string `json:"pod,omitempty"`
+	Node      string `json:"node,omitempty"`
+	Target    string `json:"target,omitempty"`
+	Reason    string `json:"reason"`
+}
+
+// A step of the pipeline walked by the explain command
+type explainStep struct {
+	Check  string `json:"check"`
+	Result string `json:"result"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// pod-rescheduler once: a single housekeeping cycle, the exit code tells whether it succeeded.
+// When the replacements are steered it waits for them, so the steering taints are removed before it exits
+func onceCommand(r *rescheduler) int {
+	_, err := r.runOnce(triggerOnce)
+	if r.steering {
+		r.readinessWaits.Wait()
+	}
+	if err != nil {
+		log.Errorf("Housekeeping cycle failed, no Pod is evicted: %s", err.Error())
+		r.notify(notify.Notification{Kind: notify.CycleFailed, Severity: notify.Error, Reason: triggerOnce, Message: err.Error()}, nil)
+		return 1
+	}
+	return 0
+}
+
+// pod-rescheduler plan: print the moves of a housekeeping cycle without executing them
+func planCommand(r *rescheduler, format string) int {
+	p := r.policy()
+	ctx, cancel := r.cycleContext()
+	defer cancel()
+	state, err := r.observe(ctx, p)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot observe the cluster: %s\n", err.Error())
+		return 1
+	}
+	entries := []planEntry{}
+	for _, action := range r.plan(state, p) {
+		if action.Type == engine.ActionMove {
+			entries = append(entries, newPlanEntry(action))
+		}
+	}
+	if err := printOutput(os.Stdout, format, entries, []string{"ACTION", "STRATEGY", "GROUP", "POD", "NODE", "TARGET", "REASON"}, func(row int) []string {
+		e := entries[row]
+		return []string{e.Action, e.Strategy, e.Group, e.Namespace + "/" + e.Pod, e.Node, e.Target, e.Reason}
+	}, len(entries)); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 2
+	}
+	return 0
+}
+
+// pod-rescheduler explain <namespace/pod|group>: print why the Pod or the Pods of the group would or would not move
+func explainCommand(r *rescheduler, args []string, format string) int {
+	if len(args) != 1 || !strings.Contains(args[0], "/") {
+		fmt.Fprintln(os.Stderr, "Usage: pod-rescheduler explain [flags] <namespace/pod|namespace/group>")
+		return 2
+	}
+	p := r.policy()
+	ctx, cancel := r.cycleContext()
+	defer cancel()
+	state, err := r.observe(ctx, p)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot observe the cluster: %s\n", err.Error())
+		return 1
+	}
+	steps, err := r.explain(ctx, args[0], state, p)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+	if err := printOutput(os.Stdout, format, steps, []string{"CHECK", "RESULT", "DETAIL"}, func(row int) []string {
+		s := steps[row]
+		return []string{s.Check, s.Result, s.Detail}
+	}, len(steps)); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 2
+	}
+	return 0
+}
+
+func (r *rescheduler) explain(ctx context.Context, name string, state *engine.Snapshot, p *policy.Config) ([]explainStep, error) {
+	if pods, ok := state.Groups()[name]; ok {
+		steps := []explainStep{{Check: "group", Result: "ok", Detail: fmt.Sprintf("%d pods on %d nodes", len(pods), countNodes(pods))}}
+		return append(steps, r.decisionSteps(r.plan(state, p), p, name, "")...), nil
+	}
+
+	parts := strings.SplitN(name, "/", 2)
+	namespace, podName := parts[0], parts[1]
+	var steps []explainStep
+	if !p.Namespaces.Contains(namespace) {
+		return append(steps, explainStep{Check: "namespace", Result: "stop", Detail: "namespace is not in the scope of the policy"}), nil
+	}
+	steps = append(steps, explainStep{Check: "namespace", Result: "ok"})
+
+	pod := findObservedPod(state, namespace, podName)
+	if pod == nil {
+		actual, err := r.client.GetPod(ctx, namespace, podName)
+		if err != nil {
+			return nil, fmt.Errorf("neither a Pod group nor a Pod is found with name %s: %s", name, err.Error())
+		}
+		detail := "pod is not scheduled yet"
+		if len(actual.Spec.NodeName) > 0 {
+			detail = fmt.Sprintf("node %s is unschedulable or tainted", actual.Spec.NodeName)
+		}
+		return append(steps, explainStep{Check: "node", Result: "stop", Detail: detail}), nil
+	}
+	steps = append(steps, explainStep{Check: "node", Result: "ok", Detail: pod.Spec.NodeName})
+
+	group := utils.GetPodGroupName(pod)
+	if group == nil {
+		return append(steps, explainStep{Check: "group", Result: "stop", Detail: "pod is not controlled by a ReplicaSet or StatefulSet"}), nil
+	}
+	steps = append(steps, explainStep{Check: "group", Result: "ok", Detail: *group})
+	decisions := r.decisionSteps(r.plan(state, p), p, *group, podName)
+	if len(decisions) == 0 {
+		decisions = append(decisions, explainStep{Check: "decision", Result: engine.ActionSkip, Detail: "the spread of the group is within its limits or another pod of the group is moved first"})
+	}
+	return append(steps, decisions...), nil
+}
+
+// The decisions of the plan about the group, limited to a single Pod if its name is given
+func (r *rescheduler) decisionSteps(actions []engine.Action, p *policy.Config, group, podName string) []explainStep {
+	var steps []explainStep
+	for _, action := range actions {
+		if action.Group != group || (len(podName) > 0 && action.Pod != nil && action.Pod.Name != podName) {
+			continue
+		}
+		steps = append(steps, explainStep{Check: "decision", Result: action.Type, Detail: describe(action)})
+		if action.Type == engine.ActionMove {
+			// the move is only executed while the maintenance windows are open
+			result := "open"
+			if !engine.EvictionAllowed(action, p, r.now()) {
+				result = "closed"
+			}
+			steps = append(steps, explainStep{Check: "maintenance window", Result: result, Detail: action.Pod.Name})
+		}
+	}
+	return steps
+}
+
+func describe(action engine.Action) string {
+	detail := action.Reason
+	if action.Pod != nil {
+		detail = action.Pod.Name + ": " + detail
+	}
+	if len(action.Target) > 0 {
+		detail += " (target: " + action.Target + ")"
+	}
+	for _, key := range sortedKeys(action.Inputs) {
+		detail += fmt.Sprintf(" %s=%s", key, action.Inputs[key])
+	}
+	return detail
+}
+
+func newPlanEntry(action engine.Action) planEntry {
+	e := planEntry{
+		Action:   action.Type,
+		Strategy: action.Strategy,
+		Group:    action.Group,
+		Target:   action.Target,
+		Reason:   action.Reason,
+	}
+	if action.Pod != nil {
+		e.Namespace = action.Pod.Namespace
+		e.Pod = action.Pod.Name
+		e.Node = action.Pod.Spec.NodeName
+	}
+	return e
+}
+
+func findObservedPod(state *engine.Snapshot, namespace, name string) *corev1.Pod {
+	for _, pods := range state.PodsPerNode {
+		for i := range pods {
+			if pods[i].Namespace == namespace && pods[i].Name == name {
+				return &pods[i]
+			}
+		}
+	}
+	return nil
+}
+
+func countNodes(pods []corev1.Pod) int {
+	nodes := make(map[string]bool)
+	for _, pod := range pods {
+		nodes[pod.Spec.NodeName] = true
+	}
+	return len(nodes)
+}
+
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Print the value as JSON or YAML, or the rows as an aligned table
+func printOutput(out io.Writer, format string, value interface{}, header []string, row func(int) []string, rows int) error {
+	switch format {
+	case outputJSON:
+		encoded, err := json.MarshalIndent(value, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(out, string(encoded))
+		return err
+	case outputYAML:
+		encoded, err := yaml.Marshal(value)
+		if err != nil {
+			return err
+		}
+		_, err = out.Write(encoded)
+		return err
+	case outputTable:
+		w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, strings.Join(header, "\t"))
+		for i := 0; i < rows; i++ {
+			fmt.Fprintln(w, strings.Join(row(i), "\t"))
+		}
+		return w.Flush()
+	}
+	return fmt.Errorf("unknown output format: %s, it must be table, json or yaml", format)
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/hortonworks/pod-rescheduler/pkg/engine"
+	"github.com/hortonworks/pod-rescheduler/policy"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// pod-rescheduler drain <node>: cordon the node and move its Pods off one at a time, each move waits for the replacement to be ready.
+// Every step reads the state from the cluster, so an interrupted drain is resumed by running the command again
+func drainCommand(r *rescheduler, args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: pod-rescheduler drain [flags] <node>")
+		return 2
+	}
+	node := args[0]
+	p := r.policy()
+	// the drain waits for the replacements itself
+	r.waitForReadiness = func(e eviction) {}
+	plan := func() ([]engine.Action, error) {
+		return r.planDrain(node, p)
+	}
+
+	actions, err := plan()
+	if err != nil {
+		log.Errorf("Cannot plan the drain of node %s: %s", node, err.Error())
+		return 1
+	}
+	if relocationBlocked(actions) {
+		log.Errorf("Node %s is not cordoned, some of its Pods cannot be moved", node)
+		return 1
+	}
+	if err := r.client.SetUnschedulable(r.ctx, node, true); err != nil {
+		log.Errorf("Cannot cordon node %s: %s", node, err.Error())
+		return 1
+	}
+	log.WithFields(log.Fields{"node": node, "pods": newProgress(actions).total}).Info("Node is cordoned, draining it")
+	return r.relocate("Drain", p, actions, plan, func(name string) bool { return name == node })
+}
+
+// How many replacements may be scheduled back to the nodes being emptied before the drain or the migration is stopped,
+// each of them would be moved again
+const maxReturnedReplacements = 3
+
+// progress of a drain or a migration, the totals are counted from the first plan
+type progress struct {
+	nodeTotal, nodeMoved   map[string]int
+	groupTotal, groupMoved map[string]int
+	total, moved           int
+}
+
+func newProgress(actions []engine.Action) *progress {
+	pr := &progress{
+		nodeTotal:  make(map[string]int),
+		nodeMoved:  make(map[string]int),
+		groupTotal: make(map[string]int),
+		groupMoved: make(map[string]int),
+	}
+	for _, action := range actions {
+		if action.Type == engine.ActionMove || action.Reason == engine.ReasonInFlight {
+			pr.nodeTotal[action.Pod.Spec.NodeName]++
+			pr.groupTotal[action.Group]++
+			pr.total++
+		}
+	}
+	return pr
+}
+
+func (pr *progress) add(action engine.Action) {
+	node := action.Pod.Spec.NodeName
+	pr.nodeMoved[node]++
+	pr.groupMoved[action.Group]++
+	pr.moved++
+	// Pods created after the first plan are counted too
+	if pr.nodeMoved[node] > pr.nodeTotal[node] {
+		pr.nodeTotal[node] = pr.nodeMoved[node]
+	}
+	if pr.groupMoved[action.Group] > pr.groupTotal[action.Group] {
+		pr.groupTotal[action.Group] = pr.groupMoved[action.Group]
+	}
+	if pr.moved > pr.total {
+		pr.total = pr.moved
+	}
+}
+
+func (pr *progress) fields() log.Fields {
+	return log.Fields{"moved": pr.moved, "total": pr.total}
+}
+
+// Move the planned Pods one at a time until every Pod is moved. The Pods of the group moved last are moved first,
+// so the groups are moved one after the other. The plan is computed again before every move.
+// The evictions wait for the maintenance windows and the eviction rate limit like the ones of the housekeeping cycles,
+// and the replacements are steered away from the source nodes when steering is enabled.
+// It stops when the replacements keep being scheduled back to the nodes being emptied, which would never end
+func (r *rescheduler) relocate(name string, p *policy.Config, actions []engine.Action, plan func() ([]engine.Action, error), emptied func(node string) bool) int {
+	pr := newProgress(actions)
+	group := ""
+	returned := 0
+	// the times of the evictions and the reason of the current wait
+	var evictions []time.Time
+	waitingFor := ""
+	for {
+		if err := r.ctx.Err(); err != nil {
+			log.WithFields(pr.fields()).Warnf("%s is interrupted, run the command again to resume it", name)
+			return 1
+		}
+		r.cycleID = r.now().UTC().Format("20060102-150405.000")
+		actions, err := plan()
+		if err != nil {
+			log.WithFields(pr.fields()).Errorf("%s is stopped, cannot plan the next move: %s", name, err.Error())
+			return 1
+		}
+		next := nextMove(actions, group)
+		if next == nil {
+			if relocationBlocked(actions) {
+				return 1
+			}
+			if remaining := len(actions) - quiet(actions); remaining > 0 {
+				log.WithFields(pr.fields()).WithField("remaining", remaining).Errorf("%s is stopped, the remaining Pods cannot be moved yet", name)
+				return 1
+			}
+			log.WithFields(pr.fields()).Infof("%s is completed", name)
+			return 0
+		}
+		if reason, delay := evictionDelay(*next, p, evictions, r.now()); delay > 0 {
+			if reason != waitingFor {
+				deferred := *next
+				deferred.Type, deferred.Reason, deferred.Inputs = engine.ActionDefer, reason, nil
+				r.Skipped(deferred)
+				log.WithFields(pr.fields()).WithField("reason", reason).Infof("%s is waiting", name)
+				waitingFor = reason
+			}
+			select {
+			case <-r.ctx.Done():
+			case <-time.After(delay):
+			}
+			continue
+		}
+		waitingFor = ""
+		evictedAt := r.now()
+		replacement, err := r.drainPod(*next, p)
+		if err != nil {
+			log.WithFields(pr.fields()).Errorf("%s is stopped: %s", name, err.Error())
+			return 1
+		}
+		if replacement == nil {
+			// the window closed since the check
+			continue
+		}
+		evictions = append(evictions, evictedAt)
+		if emptied(replacement.Spec.NodeName) {
+			returned++
+			entry := log.WithFields(pr.fields()).WithFields(log.Fields{"pod": next.Pod.Name, "replacement": replacement.Name, "node": replacement.Spec.NodeName})
+			if returned >= maxReturnedReplacements {
+				entry.Errorf("%s is stopped, %d replacements were scheduled back to the nodes being emptied", name, returned)
+				return 1
+			}
+			entry.Warn("Replacement is scheduled back to a node being emptied, it is moved again")
+			continue
+		}
+		pr.add(*next)
+		node := next.Pod.Spec.NodeName
+		group = next.Group
+		log.WithFields(pr.fields()).WithFields(log.Fields{
+			"node":       node,
+			"nodeMoved":  pr.nodeMoved[node],
+			"nodeTotal":  pr.nodeTotal[node],
+			"group":      group,
+			"groupMoved": pr.groupMoved[group],
+			"groupTotal": pr.groupTotal[group],
+		}).Infof("%s progress", name)
+	}
+}
+
+// The next move of the group if there is one, otherwise the first move
+func nextMove(actions []engine.Action, group string) *engine.Action {
+	var first *engine.Action
+	for i := range actions {
+		if actions[i].Type != engine.ActionMove {
+			continue
+		}
+		if actions[i].Group == group {
+			return &actions[i]
+		}
+		if first == nil {
+			first = &actions[i]
+		}
+	}
+	return first
+}
+
+// Plan the drain on a fresh snapshot of the cluster
+func (r *rescheduler) planDrain(node string, p *policy.Config) ([]engine.Action, error) {
+	ctx, cancel := r.cycleContext()
+	defer cancel()
+	snapshot, err := r.observe(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	if findNode(snapshot.Nodes, node) == nil {
+		return nil, fmt.Errorf("node %s is not found", node)
+	}
+	return engine.Drain(snapshot, r.state(), node, p), nil
+}
+
+// Report the Pods which cannot be moved off their nodes, true if there is any
+func relocationBlocked(actions []engine.Action) bool {
+	blocked := false
+	for _, action := range actions {
+		if action.Type == engine.ActionSkip && !action.Quiet() {
+			decisionLog(action.Strategy, action.Group, action.Pod).WithField("reason", action.Reason).Errorf("Pod cannot be moved off node %s", action.Pod.Spec.NodeName)
+			blocked = true
+		}
+	}
+	return blocked
+}
+
+func quiet(actions []engine.Action) int {
+	count := 0
+	for _, action := range actions {
+		if action.Quiet() {
+			count++
+		}
+	}
+	return count
+}
+
+// How often a closed maintenance window is checked again during a drain or a migration
+const windowPollInterval = time.Minute
+
+// How long the eviction has to wait and why: the maintenance windows are closed, or the evictions of the last
+// housekeeping interval reached the eviction limit of a cycle. Zero when the Pod can be evicted now
+func evictionDelay(action engine.Action, p *policy.Config, evictions []time.Time, now time.Time) (string, time.Duration) {
+	if !engine.EvictionAllowed(action, p, now) {
+		return engine.ReasonOutsideWindow, windowPollInterval
+	}
+	limit := p.RateLimits.MaxEvictionsPerCycle
+	if limit <= 0 || len(evictions) < limit {
+		return "", 0
+	}
+	if delay := evictions[len(evictions)-limit].Add(p.HousekeepingInterval.Duration).Sub(now); delay > 0 {
+		return engine.ReasonRateLimit, delay
+	}
+	return "", 0
+}
+
+// The outcome of the single move executed by drainPod, it is reported to the rescheduler as well
+type drainOutcome struct {
+	*rescheduler
+	evicted bool
+	err     error
+}
+
+func (o *drainOutcome) Evicted(action engine.Action, err error) {
+	o.evicted, o.err = true, err
+	o.rescheduler.Evicted(action, err)
+}
+
+// Evict the Pod like the housekeeping cycles do and wait until its replacement is Running and Ready.
+// No replacement and no error is returned when the maintenance windows closed and the Pod was not evicted
+func (r *rescheduler) drainPod(action engine.Action, p *policy.Config) (*corev1.Pod, error) {
+	e := eviction{action: action, at: r.now(), cycleID: r.cycleID}
+	outcome := &drainOutcome{rescheduler: r}
+	ctx, cancel := r.cycleContext()
+	engine.Execute(ctx, []engine.Action{action}, r.client, outcome, p, r.now)
+	cancel()
+	if !outcome.evicted || outcome.err != nil {
+		return nil, outcome.err
+	}
+	defer r.podsBeingProcessed.Remove(action.Pod)
+	log.Infof("Waiting for the replacement of pod %s to be ready", action.Pod.Name)
+	return r.waitForReplacement(r.ctx, e)
+}
+
+func findNode(nodes []corev1.Node, name string) *corev1.Node {
+	for i := range nodes {
+		if nodes[i].Name == name {
+			return &nodes[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hortonworks/pod-rescheduler/pkg/engine"
+	"github.com/hortonworks/pod-rescheduler/policy"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestEvictionDelay(t *testing.T) {
+	now := time.Date(2018, 3, 1, 12, 0, 0, 0, time.UTC)
+	ago := func(durations ...time.Duration) []time.Time {
+		var times []time.Time
+		for _, d := range durations {
+			times = append(times, now.Add(-d))
+		}
+		return times
+	}
+	cases := []struct {
+		name      string
+		windows   []policy.MaintenanceWindow
+		limit     int
+		evictions []time.Time
+		reason    string
+		delay     time.Duration
+	}{
+		{name: "no limits", evictions: ago(3*time.Second, 2*time.Second, time.Second)},
+		{name: "window is open", windows: []policy.MaintenanceWindow{{Start: "11:00", End: "13:00"}}},
+		{
+			name:    "window is closed",
+			windows: []policy.MaintenanceWindow{{Start: "01:00", End: "05:00"}},
+			reason:  engine.ReasonOutsideWindow,
+			delay:   windowPollInterval,
+		},
+		{name: "below the eviction limit", limit: 2, evictions: ago(time.Hour, 5*time.Second)},
+		{
+			name:      "eviction limit is reached within the housekeeping interval",
+			limit:     2,
+			evictions: ago(time.Hour, 4*time.Second, time.Second),
+			reason:    engine.ReasonRateLimit,
+			delay:     6 * time.Second,
+		},
+		{name: "eviction limit was reached before the housekeeping interval", limit: 2, evictions: ago(time.Hour, 11*time.Second, 10*time.Second)},
+	}
+	pod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-0", Namespace: "default"}}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p := defaultPolicy()
+			p.HousekeepingInterval = metav1.Duration{Duration: 10 * time.Second}
+			p.MaintenanceWindows.Windows = c.windows
+			p.RateLimits.MaxEvictionsPerCycle = c.limit
+			if err := p.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			action := engine.Action{Type: engine.ActionMove, Strategy: engine.DrainStrategy, Group: "default/web", Pod: &pod}
+			if reason, delay := evictionDelay(action, p, c.evictions, now); reason != c.reason || delay != c.delay {
+				t.Errorf("expected to wait %v for %q, got %v for %q", c.delay, c.reason, delay, reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+// Reasons of the Events posted on the Pods and their controllers
+const (
+	eventReasonRescheduled     = "Rescheduled"
+	eventReasonNoCandidateNode = "NoCandidateNode"
+)
+
+// eventRecorder posts an Event on the Pod and on its controller for every rescheduling decision.
+// The same Event of an object is posted again only after the repeat interval, so that it is not repeated every cycle
+type eventRecorder struct {
+	recorder       record.EventRecorder
+	warnSkipped    bool
+	repeatInterval time.Duration
+	posted         map[string]time.Time
+	mutex          sync.Mutex
+}
+
+func newEventRecorder(clientSet kubernetes.Interface, warnSkipped bool, repeatInterval time.Duration) *eventRecorder {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: clientSet.CoreV1().Events(metav1.NamespaceAll)})
+	return &eventRecorder{
+		recorder:       broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: "pod-rescheduler"}),
+		warnSkipped:    warnSkipped,
+		repeatInterval: repeatInterval,
+		posted:         make(map[string]time.Time),
+	}
+}
+
+// Events are not posted, e.g. by the commands which do not run against a cluster
+func newNoopEventRecorder() *eventRecorder {
+	return &eventRecorder{recorder: &record.FakeRecorder{}, posted: make(map[string]time.Time)}
+}
+
+// Pod evicted in order to reschedule it to the target node
+func (e *eventRecorder) podMoved(pod *corev1.Pod, target, strategy, reason string) {
+	message := fmt.Sprintf("Pod %s evicted from node %s to be rescheduled to node %s by strategy %s: %s",
+		pod.Name, pod.Spec.NodeName, target, strategy, reason)
+	e.post(pod, corev1.EventTypeNormal, eventReasonRescheduled, message)
+}
+
+// Pod which should be moved but it cannot be, only posted when the warnings are enabled
+func (e *eventRecorder) podSkipped(pod *corev1.Pod, eventReason, strategy, reason string) {
+	if !e.warnSkipped {
+		return
+	}
+	message := fmt.Sprintf("Pod %s on node %s is not rescheduled by strategy %s: %s", pod.Name, pod.Spec.NodeName, strategy, reason)
+	e.post(pod, corev1.EventTypeWarning, eventReason, message)
+}
+
+func (e *eventRecorder) post(pod *corev1.Pod, eventType, eventReason, message string) {
+	if e.shouldPost(string(pod.UID), eventReason, message) {
+		e.recorder.Event(pod, eventType, eventReason, message)
+	}
+	if owner := controllerOf(pod); owner != nil && e.shouldPost(string(owner.UID), eventReason, message) {
+		e.recorder.Event(owner, eventType, eventReason, message)
+	}
+}
+
+func (e *eventRecorder) shouldPost(uid, eventReason, message string) bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	now := time.Now()
+	for key, posted := range e.posted {
+		if now.Sub(posted) >= e.repeatInterval {
+			delete(e.posted, key)
+		}
+	}
+	key := uid + "/" + eventReason + "/" + message
+	if _, found := e.posted[key]; found {
+		log.Debugf("Event %s was already posted on %s", eventReason, uid)
+		return false
+	}
+	e.posted[key] = now
+	return true
+}
+
+// The reference of the ReplicaSet/StatefulSet controlling the Pod
+func controllerOf(pod *corev1.Pod) *corev1.ObjectReference {
+	for _, owner := range pod.OwnerReferences {
+		if owner.Controller != nil && *owner.Controller {
+			return &corev1.ObjectReference{
+				APIVersion: owner.APIVersion,
+				Kind:       owner.Kind,
+				Name:       owner.Name,
+				Namespace:  pod.Namespace,
+				UID:        owner.UID,
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// health tracks the progress of the housekeeping loop and the connection to the API server
+type health struct {
+	mutex      sync.RWMutex
+	lastCycle  time.Time
+	multiplier int
+}
+
+func newHealth(multiplier int) *health {
+	// the first cycle is expected within the same deadline as the following ones
+	return &health{lastCycle: time.Now(), multiplier: multiplier}
+}
+
+func (h *health) cycleCompleted() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.lastCycle = time.Now()
+}
+
+// The loop is considered alive as long as a housekeeping cycle completes within a multiple of the interval
+func (h *health) live(interval time.Duration) error {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	deadline := time.Duration(h.multiplier) * interval
+	if since := time.Since(h.lastCycle); since > deadline {
+		return fmt.Errorf("no housekeeping cycle completed in the last %v (limit: %v)", since, deadline)
+	}
+	return nil
+}
+
+// The rescheduler is ready when it can reach the API server
+func (r *rescheduler) ready(ctx context.Context) error {
+	if r.ctx.Err() != nil {
+		return fmt.Errorf("shutting down")
+	}
+	if _, err := r.client.ServerVersion(ctx); err != nil {
+		return fmt.Errorf("kubernetes API is not reachable: %s", err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/hortonworks/pod-rescheduler/cluster"
+	"github.com/hortonworks/pod-rescheduler/metrics"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// instrumentedClient records the metrics of the API calls and logs the failed ones
+type instrumentedClient struct {
+	cluster.Client
+}
+
+func (c *instrumentedClient) ListNodes(ctx context.Context) ([]corev1.Node, error) {
+	start := time.Now()
+	nodes, err := c.Client.ListNodes(ctx)
+	metrics.APICall("list", "nodes", start, err)
+	return nodes, err
+}
+
+func (c *instrumentedClient) WatchNodes(ctx context.Context) (watch.Interface, error) {
+	start := time.Now()
+	watcher, err := c.Client.WatchNodes(ctx)
+	metrics.APICall("watch", "nodes", start, err)
+	return watcher, err
+}
+
+func (c *instrumentedClient) ListPods(ctx context.Context, namespace, node string) ([]corev1.Pod, error) {
+	log.WithField("node", node).Debug("List Pods")
+	start := time.Now()
+	pods, err := c.Client.ListPods(ctx, namespace, node)
+	metrics.APICall("list", "pods", start, err)
+	if err != nil {
+		log.WithField("node", node).Errorf("Failed to list Pods: %s", err.Error())
+	}
+	return pods, err
+}
+
+func (c *instrumentedClient) GetPod(ctx context.Context, namespace, name string) (*corev1.Pod, error) {
+	start := time.Now()
+	pod, err := c.Client.GetPod(ctx, namespace, name)
+	metrics.APICall("get", "pods", start, err)
+	return pod, err
+}
+
+func (c *instrumentedClient) DeletePod(ctx context.Context, namespace, name string) error {
+	start := time.Now()
+	err := c.Client.DeletePod(ctx, namespace, name)
+	metrics.APICall("delete", "pods", start, err)
+	return err
+}
+
+func (c *instrumentedClient) GetNode(ctx context.Context, name string) (*corev1.Node, error) {
+	start := time.Now()
+	node, err := c.Client.GetNode(ctx, name)
+	metrics.APICall("get", "nodes", start, err)
+	return node, err
+}
+
+func (c *instrumentedClient) UpdateNode(ctx context.Context, node *corev1.Node) error {
+	start := time.Now()
+	err := c.Client.UpdateNode(ctx, node)
+	metrics.APICall("update", "nodes", start, err)
+	return err
+}
+
+func (c *instrumentedClient) SetUnschedulable(ctx context.Context, node string, unschedulable bool) error {
+	start := time.Now()
+	err := c.Client.SetUnschedulable(ctx, node, unschedulable)
+	metrics.APICall("patch", "nodes", start, err)
+	return err
+}
+
+func (c *instrumentedClient) ServerVersion(ctx context.Context) (string, error) {
+	start := time.Now()
+	version, err := c.Client.ServerVersion(ctx)
+	metrics.APICall("get", "version", start, err)
+	return version, err
+}
@@ -2,214 +2,336 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	log "github.com/Sirupsen/logrus"
+	"github.com/hortonworks/pod-rescheduler/audit"
+	"github.com/hortonworks/pod-rescheduler/cluster"
+	"github.com/hortonworks/pod-rescheduler/notify"
+	"github.com/hortonworks/pod-rescheduler/policy"
 	utils "github.com/hortonworks/pod-rescheduler/utils"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/fields"
-	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
-	"text/tabwriter"
 )
 
 var (
-	Version              string
-	BuildTime            string
-	housekeepingInterval = flag.Duration("housekeeping-interval", 10*time.Second, `How often rescheduler takes actions.`)
-	namespace            = flag.String("namespace", metav1.NamespaceDefault, `Namespace to watch for Pods.`)
-	minReplica           = flag.Int("min-replica-count", 2, "Minimum number or replicas that a replica set or replication controller should have to allow their pods deletion in scale down")
-	podSchedulingTimeout = flag.Duration("pod-scheduled-timeout", 1*time.Minute, "How long should the rescheduler wait for a Pod to be scheduled")
+	Version                  string
+	BuildTime                string
+	housekeepingInterval     = flag.Duration("housekeeping-interval", 10*time.Second, `How often rescheduler takes actions.`)
+	nodeEvents               = flag.Bool("node-events", true, "Run a housekeeping cycle when a node is added, becomes Ready, is uncordoned or a taint is removed from it, not only in every housekeeping interval")
+	nodeEventDebounce        = flag.Duration("node-event-debounce", 5*time.Second, "The node changes within this period after the first one trigger a single housekeeping cycle")
+	nodeEventMinInterval     = flag.Duration("node-event-min-interval", 30*time.Second, "Minimum time between the housekeeping cycles triggered by node changes")
+	namespace                = flag.String("namespace", metav1.NamespaceDefault, `Namespace to watch for Pods. The Pods of every namespace are listed with a ClusterRole, with a namespaced Role only the Pods of the included namespaces are seen`)
+	minReplica               = flag.Int("min-replica-count", 2, "Minimum number or replicas that a replica set or replication controller should have to allow their pods deletion in scale down")
+	maxSkew                  = flag.Int("max-skew", 1, "Maximum difference between the Pods of a group on the most and the least loaded node which can run them")
+	maxPodsPerNode           = flag.Int("max-pods-per-node", 0, "(optional) maximum number of Pods of a group on a node, 0 means no limit")
+	nodePoolLabel            = flag.String("node-pool-label", "", "(optional) label of the nodes naming their pool, the Pods are moved only between the nodes of the same pool")
+	preferNewNodes           = flag.Bool("prefer-new-nodes", false, "Move the Pods to the nodes created within --new-node-max-age, e.g. the ones the cluster-autoscaler just added, when there is one")
+	newNodeMaxAge            = flag.Duration("new-node-max-age", 10*time.Minute, "How long a node is preferred as a target by --prefer-new-nodes after it is created")
+	workers                  = flag.Int("workers", 4, "Number of Pod groups evaluated in parallel, their moves are applied one by one")
+	podSchedulingTimeout     = flag.Duration("pod-scheduled-timeout", 1*time.Minute, "How long should the rescheduler wait for a Pod to be scheduled")
+	listenAddress            = flag.String("listen-address", ":8080", "Address of the HTTP server serving the /metrics, /healthz and /readyz endpoints")
+	warnSkippedPods          = flag.Bool("warn-skipped-pods", false, "Post Warning Events on the Pods which should be moved but cannot be")
+	eventRepeatInterval      = flag.Duration("event-repeat-interval", 30*time.Minute, "How long the same Event is not posted again on the same object")
+	livenessMultiplier       = flag.Int("liveness-interval-multiplier", 5, "The /healthz endpoint fails when no housekeeping cycle completed within this many housekeeping intervals")
+	kubeconfig               = flag.String("kubeconfig", defaultKubeConfig(), "(optional) absolute path to the kubeconfig file, used outside of the cluster")
+	logFormat                = flag.String("log-format", utils.LogFormatText, "Log format: text, json or logfmt")
+	logLevel                 = flag.String("log-level", "info", "Log level: debug, info, warning, error, fatal or panic")
+	auditLogFile             = flag.String("audit-log-file", "", "(optional) path of the JSON lines audit log of every decision, the quiet ones are recorded only with --audit-quiet-decisions")
+	auditQuietDecisions      = flag.Bool("audit-quiet-decisions", false, "Record the decisions of the normal operation in the audit log as well: the balanced groups and the Pods which are not ready or belong to a DaemonSet. They are recorded in every cycle, so the log grows much faster")
+	auditLogMaxSize          = flag.Int("audit-log-max-size", 100, "Size in megabytes after the audit log file is rotated")
+	auditLogMaxBackups       = flag.Int("audit-log-max-backups", 5, "How many rotated audit log files are kept")
+	policyConfigFile         = flag.String("policy-config-file", "", "(optional) path to a YAML policy file, it overrides the flags. It is read again at the start of every housekeeping cycle and applied when changed")
+	output                   = flag.String("output", outputTable, "Output format of the plan, explain and simulate commands: table, json or yaml")
+	snapshotFile             = flag.String("snapshot", "", "Cluster snapshot file written by the snapshot command (standard output if empty) and read by the simulate command")
+	simulationCycles         = flag.Int("cycles", 10, "Number of housekeeping cycles run by the simulate command")
+	nodeConditions           = flag.String("node-conditions", "", "(optional) comma separated node condition types, e.g. MemoryPressure,DiskPressure,KernelDeadlock. Pods are moved off the nodes where one of them is True")
+	nodeConditionMinDuration = flag.Duration("node-condition-min-duration", 2*time.Minute, "How long a node condition has to be True before the Pods are moved off the node")
+	shutdownGracePeriod      = flag.Duration("shutdown-grace-period", 30*time.Second, "How long the current cycle and the readiness waits can take after SIGTERM or SIGINT before the process exits")
+	kubeAPIQPS               = flag.Float64("kube-api-qps", float64(rest.DefaultQPS), "Maximum queries per second to the Kubernetes API server")
+	kubeAPIBurst             = flag.Int("kube-api-burst", rest.DefaultBurst, "Maximum burst of queries to the Kubernetes API server above --kube-api-qps")
+	kubeAPIRetries           = flag.Int("kube-api-retries", 3, "How many times a Kubernetes API call failed with a transient error is retried, Pod deletions are never retried")
+	kubeAPIRetryBackoff      = flag.Duration("kube-api-retry-backoff", 500*time.Millisecond, "Wait before the first retry of a Kubernetes API call, doubled for every further retry and extended with a random jitter")
+	podListPageSize          = flag.Int64("pod-list-page-size", 500, "Pods per page when the Pods of every node are listed at once, 0 lists them in a single page")
+	cycleDeadline            = flag.Duration("cycle-deadline", 1*time.Minute, "Deadline of the Kubernetes API calls of a housekeeping cycle")
+	sourceNodeSelector       = flag.String("source-node-selector", "", "Label selector of the nodes the migrate command moves the Pods off, e.g. pool=old")
+	targetNodeSelector       = flag.String("target-node-selector", "", "Label selector of the nodes the migrate command moves the Pods to, e.g. pool=new")
+	cordonSource             = flag.Bool("cordon-source", false, "Cordon every node of the source pool before the migrate command moves the Pods, otherwise the migration stops when the replacements keep being scheduled back into the source pool, unless --steer-replacements steers them away")
+	webhookURL               = flag.String("webhook-url", "", "(optional) URL the notifications are posted to as JSON: evictions, groups skipped with a warning, readiness timeouts and failed cycles")
+	webhookHeaders           = newHeaderFlag("webhook-header", "HTTP header of the webhook requests as Name: value, it can be repeated")
+	webhookNamespaces        = flag.String("webhook-namespaces", "", "(optional) comma separated namespaces of the Pods the webhook is notified about, empty means every namespace")
+	webhookStrategies        = flag.String("webhook-strategies", "", "(optional) comma separated strategies the webhook is notified about, e.g. spread,node_conditions, empty means every strategy")
+	webhookMinSeverity       = flag.String("webhook-min-severity", "info", "Lowest severity the webhook is notified about: info, warning or error")
+	notifyBatchSize          = flag.Int("notification-batch-size", 20, "Most notifications sent in a single request")
+	notifyBatchInterval      = flag.Duration("notification-batch-interval", 10*time.Second, "How long the notifications are collected before they are sent")
+	notifyRetries            = flag.Int("notification-retries", 3, "How many times a failed notification request is retried")
+	notifyRepeatInterval     = flag.Duration("notification-repeat-interval", 30*time.Minute, "How long the same notification is not sent again, e.g. a group which cannot be moved in every cycle, 0 sends every one")
+	steerReplacements        = flag.Bool("steer-replacements", false, "Taint the source node of an eviction with a PreferNoSchedule taint until the replacement is scheduled or --pod-scheduled-timeout expires, so the scheduler prefers the other nodes")
+	steeringID               = flag.String("steering-id", defaultSteeringID, "Identity of the rescheduler in the values of its steering taints. The once, drain, migrate and run commands remove the taints left with this identity at the start, so the instances running at the same time, e.g. a run Deployment and a once CronJob, need different identities")
+	targetMissExpiry         = flag.Duration("target-miss-expiry", 1*time.Hour, "How long a target node missed by the replacement of a Pod is penalized as a target of its group")
 )
 
-func main() {
-	formatter := &utils.TimeFormatter{}
-	log.SetFormatter(formatter)
+const usage = `Usage: pod-rescheduler [command] [flags]
 
-	log.Infof("Started pod-rescheduler application %s-%s", Version, BuildTime)
+Commands:
+  run                     reschedule Pods in every housekeeping interval and on node changes (default)
+  once                    run a single housekeeping cycle and exit
+  plan                    print the moves of a housekeeping cycle without executing them
+  explain <namespace/pod|namespace/group>
+                          print why a Pod or the Pods of a group would or would not move
+  drain <node>            cordon the node and move its Pods off one at a time, run it again to resume
+  migrate --source-node-selector <selector> --target-node-selector <selector>
+                          move the Pods of the source pool into the target pool one group at a time
+  snapshot                write the Nodes, Pods, controllers and PodDisruptionBudgets to the --snapshot file
+  simulate --snapshot <file>
+                          run housekeeping cycles against a snapshot with a simulated scheduler
+  audit query             print the matching records of the audit log
+  version                 print the version and the build time
 
-	config, err := rest.InClusterConfig()
-	if err != nil {
-		log.Warnf("Cannot use service account (/var/run/secrets/kubernetes.io/serviceaccount/" +
-			corev1.ServiceAccountTokenKey + ") trying to connect with kube config file..")
-	}
+Flags:
+`
 
-	if config == nil {
-		var kubeconfig *string
-		if home := homeDir(); home != "" {
-			kubeconfig = flag.String("kubeconfig", filepath.Join(home, ".kube", "config"), "(optional) absolute path to the kubeconfig file")
-		} else {
-			kubeconfig = flag.String("kubeconfig", "", "absolute path to the kubeconfig file")
-		}
-		log.Infof("Use kube config: %s", *kubeconfig)
-		config, err = clientcmd.BuildConfigFromFlags("", *kubeconfig)
-		if err != nil {
-			panic(err.Error())
-		}
+func main() {
+	command, args := "run", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
 	}
-	flag.Parse()
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	switch command {
+	case "audit":
+		os.Exit(auditCommand(args))
+	case "version":
+		fmt.Printf("pod-rescheduler %s (built at %s)\n", Version, BuildTime)
+		return
+	case "run", "once", "plan", "explain", "snapshot", "simulate", "drain", "migrate":
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command: %s\n", command)
+		flag.Usage()
+		os.Exit(2)
+	}
+	args = parseFlags(args)
 
-	clientSet, err := kubernetes.NewForConfig(config)
-	if err != nil {
-		panic(err.Error())
+	// these commands print their result on the standard output
+	logOutput := os.Stdout
+	if command == "plan" || command == "explain" || command == "snapshot" || command == "simulate" {
+		logOutput = os.Stderr
+	}
+	if err := utils.ConfigureLogging(logOutput, *logFormat, *logLevel); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid logging arguments: %s\n", err.Error())
+		os.Exit(2)
+	}
+	if *output != outputTable && *output != outputJSON && *output != outputYAML {
+		fmt.Fprintf(os.Stderr, "Invalid output format: %s, it must be table, json or yaml\n", *output)
+		os.Exit(2)
 	}
 
-	log.Info("Kubernetes client initialized")
+	log.Infof("Started pod-rescheduler application %s-%s", Version, BuildTime)
 	log.Info("Namespace: ", *namespace)
-	log.Info("Housekeeping interval: ", housekeepingInterval)
+	log.Info("Housekeeping interval: ", *housekeepingInterval)
 	log.Info("Minimum replica count: ", *minReplica)
-	log.Info("Pod scheduling timeout: ", podSchedulingTimeout)
+	log.Info("Pod scheduling timeout: ", *podSchedulingTimeout)
 
-	podClient := clientSet.CoreV1().Pods(*namespace)
-	nodeClient := clientSet.Nodes()
-
-	tabWriter := tabwriter.NewWriter(os.Stdout, 0, 0, 1, '.', tabwriter.Debug)
-	log.SetOutput(tabWriter)
-
-	for {
-		select {
-		case <-time.After(*housekeepingInterval):
-			{
-				nodes, err := nodeClient.List(metav1.ListOptions{})
-				if err != nil {
-					log.Errorf("Node list error: %s", err.Error())
-					continue
-				}
-
-				var podsPerNode = make(map[string][]corev1.Pod)
-				var allPods = make([]corev1.Pod, 0)
-				for _, node := range nodes.Items {
-					// ignore tainted nodes for now..
-					if !node.Spec.Unschedulable && len(node.Spec.Taints) == 0 {
-						pods := listPodsOnNode(podClient.List, node)
-						podsPerNode[node.Name] = pods
-						allPods = append(allPods, pods...)
-					}
-				}
-
-				podGroups := groupPods(allPods)
-				logPods(podGroups)
-				for group, pods := range podGroups {
-					if pod := findMovablePod(pods); pod != nil {
-						log.Infof("Find node candidate for Pod: %s", pod.Name)
-						if node := findNodeForPod(podsPerNode, group, nodes.Items); node != nil {
-							// consider Taints and Tolerations to make sure it gets scheduled to the desired node
-							log.Infof("Delete Pod (%s) in order to reschedule it to another node", pod.Name)
-							err := podClient.Delete(pod.Name, &metav1.DeleteOptions{})
-							if err != nil {
-								log.Errorf("Failed to delete Pod: %s, error: %s", pod.Name, err.Error())
-							}
-						} else {
-							log.Infof("There is no node candidate to move the Pod (%s) to", pod.Name)
-						}
-					} else {
-						log.Infof("No action required for Pod group: %s", group)
-					}
-				}
-			}
+	if *livenessMultiplier < 1 {
+		log.Fatalf("Invalid liveness interval multiplier: %d, it must be at least 1", *livenessMultiplier)
+	}
+	if *nodeEventDebounce < 0 || *nodeEventMinInterval < 0 {
+		log.Fatalf("Invalid node event debounce or minimum interval: %v, %v, they must not be negative", *nodeEventDebounce, *nodeEventMinInterval)
+	}
+	if *kubeAPIQPS <= 0 || *kubeAPIBurst < 1 {
+		log.Fatalf("Invalid Kubernetes API rate limit: %v queries per second with a burst of %d, they must be positive", *kubeAPIQPS, *kubeAPIBurst)
+	}
+	if *kubeAPIRetries < 0 || *kubeAPIRetryBackoff <= 0 {
+		log.Fatalf("Invalid Kubernetes API retries: %d with a backoff of %v, the retries must not be negative and the backoff must be positive", *kubeAPIRetries, *kubeAPIRetryBackoff)
+	}
+	if *podListPageSize < 0 {
+		log.Fatalf("Invalid Pod list page size: %d, it must not be negative", *podListPageSize)
+	}
+	if errs := validation.IsValidLabelValue(*steeringID); len(*steeringID) == 0 || len(errs) > 0 {
+		log.Fatalf("Invalid steering identity: %q, it must be a non-empty label value: %s", *steeringID, strings.Join(errs, ", "))
+	}
+	if *cycleDeadline <= 0 {
+		log.Fatalf("Invalid cycle deadline: %v, it must be positive", *cycleDeadline)
+	}
+	defaults := defaultPolicy()
+	var policyWatcher *policy.Watcher
+	if len(*policyConfigFile) > 0 {
+		var err error
+		policyWatcher, err = policy.NewWatcher(*policyConfigFile, *defaults)
+		if err != nil {
+			log.Fatalf("Cannot load policy: %s", err.Error())
 		}
+		log.Info("Policy file: ", *policyConfigFile)
+	}
+	if command == "simulate" {
+		os.Exit(simulateCommand(defaults, policyWatcher, *snapshotFile, *simulationCycles, *output))
 	}
-}
 
-func logPods(podGroups map[string][]corev1.Pod) {
-	for _, pods := range podGroups {
-		for _, pod := range pods {
-			log.Infof("%s\t%s\t%s\t%s", pod.Name, pod.Status.Phase, pod.Status.PodIP, pod.Spec.NodeName)
+	clientSet := newClientSet()
+	log.Info("Kubernetes client initialized")
+	if command == "snapshot" {
+		namespace := defaults.Namespaces.ListNamespace()
+		if policyWatcher != nil {
+			namespace = policyWatcher.Current().Namespaces.ListNamespace()
 		}
+		os.Exit(snapshotCommand(clientSet, namespace, *snapshotFile))
 	}
-}
 
-// Group the Pods that belong to the same Deployment/StatefulSet
-// Single Pods are ignored
-func groupPods(pods []corev1.Pod) (result map[string][]corev1.Pod) {
-	result = make(map[string][]corev1.Pod)
-	for _, pod := range pods {
-		groupName := utils.GetPodGroupName(&pod)
-		if groupName != nil {
-			result[*groupName] = append(result[*groupName], pod)
+	ctx := shutdownContext(*shutdownGracePeriod)
+	r := newRescheduler(ctx, cluster.NewClient(clientSet, *podListPageSize), defaults, policyWatcher)
+	switch command {
+	case "plan":
+		os.Exit(planCommand(r, *output))
+	case "explain":
+		os.Exit(explainCommand(r, args, *output))
+	}
+	r.events = newEventRecorder(clientSet, *warnSkippedPods, *eventRepeatInterval)
+	if len(*auditLogFile) > 0 {
+		var err error
+		r.auditLog, err = audit.NewLog(*auditLogFile, int64(*auditLogMaxSize)*1024*1024, *auditLogMaxBackups)
+		if err != nil {
+			log.Fatalf("Cannot open audit log: %s", err.Error())
 		}
+		r.auditQuiet = *auditQuietDecisions
+		log.Info("Audit log: ", *auditLogFile)
+	}
+	notifier, err := newNotifier()
+	if err != nil {
+		log.Fatalf("Cannot configure the notifications: %s", err.Error())
+	}
+	r.notifier = notifier
+	r.steering, r.steeringID = *steerReplacements, *steeringID
+	if err := r.removeStaleSteeringTaints(); err != nil {
+		log.Fatalf("Cannot remove the stale steering taints: %s", err.Error())
+	}
+	switch command {
+	case "once":
+		code := onceCommand(r)
+		r.close()
+		os.Exit(code)
+	case "drain":
+		code := drainCommand(r, args)
+		r.close()
+		os.Exit(code)
+	case "migrate":
+		code := migrateCommand(r, args)
+		r.close()
+		os.Exit(code)
 	}
-	return result
+	if *nodeEvents {
+		r.triggers = watchNodes(ctx, r.client, *nodeEventDebounce, *nodeEventMinInterval)
+		log.Infof("Node events trigger housekeeping cycles, debounced for %v and at least %v apart", *nodeEventDebounce, *nodeEventMinInterval)
+	}
+	server := startHTTPServer(*listenAddress, r)
+	r.run()
+	r.close()
+	stopHTTPServer(server)
+	log.Info("Stopped pod-rescheduler")
 }
 
-// Find a Pod which has an alternative Running and Ready Pod on the same node
-// Pods can be moved only when the minimum replica count is met
-func findMovablePod(pods []corev1.Pod) *corev1.Pod {
-	var podsByNode = make(map[string][]corev1.Pod)
-	var podCandidate *corev1.Pod
-	podCount := 0
-	for i, pod := range pods {
-		containerStatuses := pod.Status.ContainerStatuses
-		if pod.Status.Phase == corev1.PodRunning && len(containerStatuses) > 0 {
-			if isPodReady(&pod) {
-				node := pod.Spec.NodeName
-				if len(podsByNode[node]) == 1 {
-					log.Infof("Pod: %s can be rescheduled as there is another running and ready pod (%s) on the same node: %s", pod.Name, podsByNode[node][0].Name, node)
-					podCandidate = &pods[i]
-				}
-				podsByNode[node] = append(podsByNode[node], pod)
-				podCount++
-			}
+// Parse the flags before and after the positional arguments of the command, the positional arguments are returned
+func parseFlags(args []string) []string {
+	var positional []string
+	for {
+		flag.CommandLine.Parse(args)
+		args = flag.Args()
+		if len(args) == 0 {
+			return positional
 		}
+		positional = append(positional, args[0])
+		args = args[1:]
 	}
-	if podCount >= *minReplica {
-		return podCandidate
-	}
-	return nil
 }
 
-func isPodReady(pod *corev1.Pod) bool {
-	for _, cStatus := range pod.Status.ContainerStatuses {
-		if !cStatus.Ready {
-			log.Infof("Pod (%s) is running, but it's container (%s) is not ready", pod.Name, cStatus.Name)
-			return false
+// Connect with the service account in the cluster or with the kube config file outside of it
+func newClientSet() *kubernetes.Clientset {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		log.Warnf("Cannot use service account (/var/run/secrets/kubernetes.io/serviceaccount/" +
+			corev1.ServiceAccountTokenKey + ") trying to connect with kube config file..")
+	}
+
+	if config == nil {
+		log.Infof("Use kube config: %s", *kubeconfig)
+		config, err = clientcmd.BuildConfigFromFlags("", *kubeconfig)
+		if err != nil {
+			panic(err.Error())
 		}
 	}
-	return true
+
+	config.QPS, config.Burst = float32(*kubeAPIQPS), *kubeAPIBurst
+	clientSet, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		panic(err.Error())
+	}
+	return clientSet
 }
 
-// Find a node which does not run any Pod from the same Deployment/StatefulSet
-func findNodeForPod(podsPerNode map[string][]corev1.Pod, group string, nodes []corev1.Node) *corev1.Node {
-	for nodeName, pods := range podsPerNode {
-		podFoundForGroup := false
+func logPods(podGroups map[string][]corev1.Pod) {
+	for _, pods := range podGroups {
 		for _, pod := range pods {
-			groupName := utils.GetPodGroupName(&pod)
-			if groupName != nil && *groupName == group {
-				log.Infof("Found Pod group(%s) on node: %s, searching..", group, nodeName)
-				podFoundForGroup = true
-				break
-			}
-		}
-		if !podFoundForGroup {
-			log.Infof("Found node: %s for Pod group: %s", nodeName, group)
-			return findNode(nodeName, nodes)
+			log.WithFields(log.Fields{
+				"namespace": pod.Namespace,
+				"pod":       pod.Name,
+				"phase":     pod.Status.Phase,
+				"ip":        pod.Status.PodIP,
+				"node":      pod.Spec.NodeName,
+			}).Debug("Pod")
 		}
 	}
-	return nil
 }
 
-func findNode(name string, nodes []corev1.Node) *corev1.Node {
-	for _, node := range nodes {
-		if node.Name == name {
-			return &node
+// Build the policy from the command line flags, it is used when no policy file is given
+// and it provides the defaults of the policy file
+func defaultPolicy() *policy.Config {
+	p := &policy.Config{
+		HousekeepingInterval: metav1.Duration{Duration: *housekeepingInterval},
+		Namespaces:           policy.NamespaceScope{Include: []string{*namespace}},
+		NodePoolLabel:        *nodePoolLabel,
+		Workers:              *workers,
+		Autoscaler: policy.Autoscaler{
+			PreferNewNodes: *preferNewNodes,
+			NewNodeMaxAge:  metav1.Duration{Duration: *newNodeMaxAge},
+		},
+		Strategies: policy.Strategies{
+			Spread: policy.SpreadStrategy{
+				Enabled:         true,
+				MinReplicaCount: *minReplica,
+				MaxSkew:         *maxSkew,
+				MaxPodsPerNode:  *maxPodsPerNode,
+			},
+		},
+	}
+	for _, conditionType := range strings.Split(*nodeConditions, ",") {
+		if conditionType = strings.TrimSpace(conditionType); len(conditionType) > 0 {
+			p.Strategies.NodeConditions.Enabled = true
+			p.Strategies.NodeConditions.Conditions = append(p.Strategies.NodeConditions.Conditions, policy.NodeCondition{
+				Type:        conditionType,
+				MinDuration: metav1.Duration{Duration: *nodeConditionMinDuration},
+			})
 		}
 	}
-	return nil
+	if err := p.Validate(); err != nil {
+		log.Fatalf("Invalid command line arguments: %s", err.Error())
+	}
+	return p
 }
 
-func listPodsOnNode(ListPodsOnNode func(opts metav1.ListOptions) (*corev1.PodList, error), node corev1.Node) []corev1.Pod {
-	log.Infof("List Pods on node: %s", node.Name)
-	podsOnNode, err := ListPodsOnNode(metav1.ListOptions{FieldSelector: fields.SelectorFromSet(fields.Set{"spec.nodeName": node.Name}).String()})
-	if err != nil {
-		log.Errorf("Failed to list Pods on node: %s", node.Name)
-		return nil
+func defaultKubeConfig() string {
+	if home := homeDir(); home != "" {
+		return filepath.Join(home, ".kube", "config")
 	}
-	return podsOnNode.Items
+	return ""
 }
 
 func homeDir() string {
@@ -219,21 +341,26 @@ func homeDir() string {
 	return os.Getenv("USERPROFILE") // windows
 }
 
-func waitForPodReadiness(getPod func(name string, options metav1.GetOptions) (*corev1.Pod, error), podsBeingProcessed *utils.PodSet, pod *corev1.Pod) {
-	podName := pod.Name
-	log.Infof("Waiting for pod %s to be scheduled", podName)
-	err := wait.Poll(2*time.Second, *podSchedulingTimeout, func() (bool, error) {
-		actualPod, err := getPod(pod.Name, metav1.GetOptions{})
-		if err != nil {
-			log.Warningf("Error while getting pod %s: %v", podName, err)
-			return false, nil
-		}
-		return isPodReady(actualPod), nil
-	})
-	if err != nil {
-		log.Warningf("Timeout while waiting for pod %s to be scheduled after %v.", podName, *podSchedulingTimeout)
+// Wait until the replacement of the Pod is ready, the wait is given up when the rescheduler shuts down
+func (r *rescheduler) waitForPodReadiness(e eviction) {
+	podName := e.action.Pod.Name
+	log.Infof("Waiting for the replacement of pod %s to be scheduled", podName)
+	replacement, err := r.waitForReplacement(r.ctx, e)
+	if r.ctx.Err() != nil {
+		log.Warningf("Gave up waiting for the replacement of pod %s to be scheduled, the rescheduler is shutting down", podName)
+	} else if err != nil {
+		log.Warningf("Timeout while waiting for the replacement of pod %s to be scheduled after %v.", podName, *podSchedulingTimeout)
+		r.notify(notify.Notification{
+			Kind:       notify.ReadinessTimeout,
+			Severity:   notify.Warning,
+			CycleID:    e.cycleID,
+			Strategy:   e.action.Strategy,
+			Group:      e.action.Group,
+			TargetNode: e.action.Target,
+			Message:    err.Error(),
+		}, e.action.Pod)
 	} else {
-		log.Infof("Pod %v was successfully scheduled.", podName)
+		log.Infof("Pod %v was successfully replaced by pod %v.", podName, replacement.Name)
 	}
-	podsBeingProcessed.Remove(pod)
+	r.podsBeingProcessed.Remove(e.action.Pod)
 }
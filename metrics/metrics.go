@@ -0,0 +1,157 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "pod_rescheduler"
+
+// Results of a housekeeping cycle or an eviction
+const (
+	Success = "success"
+	Error   = "error"
+)
+
+var (
+	cycleDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "cycle_duration_seconds",
+		Help:      "Duration of the housekeeping cycles.",
+		Buckets:   prometheus.ExponentialBuckets(0.05, 2, 12),
+	})
+	cycles = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cycles_total",
+		Help:      "Number of housekeeping cycles by what triggered them and result.",
+	}, []string{"trigger", "result"})
+	lastCycle = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_cycle_timestamp_seconds",
+		Help:      "Unix time of the last completed housekeeping cycle.",
+	})
+	apiDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "api_request_duration_seconds",
+		Help:      "Latency of the Kubernetes API calls.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"verb", "resource"})
+	apiErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "api_request_errors_total",
+		Help:      "Number of failed Kubernetes API calls.",
+	}, []string{"verb", "resource"})
+	apiRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "api_request_retries_total",
+		Help:      "Number of Kubernetes API calls retried after a transient error.",
+	}, []string{"verb", "resource"})
+	evictions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "evictions_total",
+		Help:      "Number of Pods evicted in order to reschedule them.",
+	}, []string{"strategy", "namespace", "pool", "result"})
+	skipped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "skipped_total",
+		Help:      "Number of Pod groups or candidates skipped by reason.",
+	}, []string{"reason"})
+	groupsInCooldown = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "groups_in_cooldown",
+		Help:      "Number of Pod groups waiting for their cooldown to expire.",
+	})
+	groupsInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "groups_in_flight",
+		Help:      "Number of Pod groups with a Pod being rescheduled.",
+	})
+	placements = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "placements_total",
+		Help:      "Number of replacement Pods by where they were placed compared to the target node: target, other, source or not_found.",
+	}, []string{"strategy", "outcome"})
+	nodeEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "node_events_total",
+		Help:      "Number of node changes which trigger a housekeeping cycle by reason: node_added, node_ready, node_uncordoned or taint_removed.",
+	}, []string{"reason"})
+	groupSkew = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "group_spread_skew",
+		Help:      "Difference between the most and the least Pods of a group on the eligible nodes of a node pool.",
+	}, []string{"group", "pool"})
+)
+
+func init() {
+	prometheus.MustRegister(cycleDuration, cycles, lastCycle, apiDuration, apiErrors, apiRetries, evictions, skipped,
+		groupsInCooldown, groupsInFlight, placements, nodeEvents, groupSkew)
+}
+
+// Cycle records a finished housekeeping cycle and what triggered it
+func Cycle(start time.Time, trigger string, err error) {
+	cycleDuration.Observe(time.Since(start).Seconds())
+	cycles.WithLabelValues(trigger, result(err)).Inc()
+	lastCycle.Set(float64(time.Now().Unix()))
+}
+
+// APICall records the latency and the outcome of a Kubernetes API call
+func APICall(verb, resource string, start time.Time, err error) {
+	apiDuration.WithLabelValues(verb, resource).Observe(time.Since(start).Seconds())
+	if err != nil {
+		apiErrors.WithLabelValues(verb, resource).Inc()
+	}
+}
+
+// APIRetry records a Kubernetes API call retried after a transient error
+func APIRetry(verb, resource string) {
+	apiRetries.WithLabelValues(verb, resource).Inc()
+}
+
+// Eviction records the outcome of a Pod eviction, the pool is empty when the nodes are not partitioned into pools
+func Eviction(strategy, podNamespace, pool string, err error) {
+	evictions.WithLabelValues(strategy, podNamespace, pool, result(err)).Inc()
+}
+
+// Skipped records a Pod group or candidate which was not moved
+func Skipped(reason string) {
+	skipped.WithLabelValues(reason).Inc()
+}
+
+// Placement records where the replacement of an evicted Pod was placed
+func Placement(strategy, outcome string) {
+	placements.WithLabelValues(strategy, outcome).Inc()
+}
+
+// NodeEvent records a node change which triggers a housekeeping cycle
+func NodeEvent(reason string) {
+	nodeEvents.WithLabelValues(reason).Inc()
+}
+
+// GroupsInCooldown sets the number of Pod groups in cooldown
+func GroupsInCooldown(count int) {
+	groupsInCooldown.Set(float64(count))
+}
+
+// GroupsInFlight sets the number of Pod groups with a Pod being rescheduled
+func GroupsInFlight(count int) {
+	groupsInFlight.Set(float64(count))
+}
+
+// GroupSkew replaces the spread skew of every Pod group by node pool, groups which are gone are dropped
+func GroupSkew(skews map[string]map[string]int) {
+	groupSkew.Reset()
+	for group, pools := range skews {
+		for pool, skew := range pools {
+			groupSkew.WithLabelValues(group, pool).Set(float64(skew))
+		}
+	}
+}
+
+func result(err error) string {
+	if err != nil {
+		return Error
+	}
+	return Success
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/hortonworks/pod-rescheduler/pkg/engine"
+	"github.com/hortonworks/pod-rescheduler/policy"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// pod-rescheduler migrate: move every Pod from the nodes of the source pool into the nodes of the target pool, one group after the other.
+// Like the drain, every step reads the state from the cluster, so an interrupted migration is resumed by running the command again.
+// The evictions wait for the maintenance windows and the eviction rate limit, and the replacements are kept off the source pool
+// by cordoning it or by steering them away
+func migrateCommand(r *rescheduler, args []string) int {
+	if len(args) != 0 {
+		fmt.Fprintln(os.Stderr, "Usage: pod-rescheduler migrate --source-node-selector <selector> --target-node-selector <selector> [flags]")
+		return 2
+	}
+	source, target, err := poolSelectors(*sourceNodeSelector, *targetNodeSelector)
+	if err != nil {
+		log.Errorf("Invalid node pools: %s", err.Error())
+		return 2
+	}
+	p := r.policy()
+	// the migration waits for the replacements itself
+	r.waitForReadiness = func(e eviction) {}
+	var sourceNodes []string
+	plan := func() ([]engine.Action, error) {
+		var actions []engine.Action
+		var err error
+		sourceNodes, actions, err = r.planMigration(source, target, p)
+		return actions, err
+	}
+
+	actions, err := plan()
+	if err != nil {
+		log.Errorf("Cannot plan the migration: %s", err.Error())
+		return 1
+	}
+	if relocationBlocked(actions) {
+		log.Error("Migration is not started, some Pods of the source pool cannot be moved")
+		return 1
+	}
+	if *cordonSource {
+		for _, node := range sourceNodes {
+			if err := r.client.SetUnschedulable(r.ctx, node, true); err != nil {
+				log.Errorf("Cannot cordon node %s: %s", node, err.Error())
+				return 1
+			}
+			log.WithField("node", node).Info("Source node is cordoned")
+		}
+	} else if r.steering {
+		log.Info("Source nodes are not cordoned, the replacements are steered away from them")
+	} else {
+		log.Warn("Source nodes are neither cordoned nor steered away from, the replacements may be scheduled back into the source pool and the migration is stopped then, use --cordon-source or --steer-replacements")
+	}
+	log.WithFields(log.Fields{"nodes": len(sourceNodes), "pods": newProgress(actions).total}).Info("Migrating the source pool")
+	return r.relocate("Migration", p, actions, plan, func(node string) bool {
+		for _, source := range sourceNodes {
+			if source == node {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// The source and the target pool selectors, both of them are required
+func poolSelectors(source, target string) (labels.Selector, labels.Selector, error) {
+	if len(source) == 0 || len(target) == 0 {
+		return nil, nil, fmt.Errorf("both --source-node-selector and --target-node-selector are required")
+	}
+	sourceSelector, err := labels.Parse(source)
+	if err != nil {
+		return nil, nil, fmt.Errorf("source node selector: %s", err.Error())
+	}
+	targetSelector, err := labels.Parse(target)
+	if err != nil {
+		return nil, nil, fmt.Errorf("target node selector: %s", err.Error())
+	}
+	return sourceSelector, targetSelector, nil
+}
+
+// Plan the migration on a fresh snapshot of the cluster, the nodes of the source pool are returned too
+func (r *rescheduler) planMigration(source, target labels.Selector, p *policy.Config) ([]string, []engine.Action, error) {
+	ctx, cancel := r.cycleContext()
+	defer cancel()
+	snapshot, err := r.observe(ctx, p)
+	if err != nil {
+		return nil, nil, err
+	}
+	var sourceNodes []string
+	targets := 0
+	for _, node := range snapshot.Nodes {
+		inSource := source.Matches(labels.Set(node.Labels))
+		inTarget := target.Matches(labels.Set(node.Labels))
+		if inSource && inTarget {
+			return nil, nil, fmt.Errorf("node %s is in both the source and the target pool", node.Name)
+		}
+		if inSource {
+			sourceNodes = append(sourceNodes, node.Name)
+		}
+		if inTarget {
+			targets++
+		}
+	}
+	if len(sourceNodes) == 0 {
+		return nil, nil, fmt.Errorf("no node matches the source node selector %s", source.String())
+	}
+	if targets == 0 {
+		return nil, nil, fmt.Errorf("no node matches the target node selector %s", target.String())
+	}
+	return sourceNodes, engine.Migrate(snapshot, r.state(), source, target, p), nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hortonworks/pod-rescheduler/audit"
+	"github.com/hortonworks/pod-rescheduler/pkg/engine"
+	"github.com/hortonworks/pod-rescheduler/policy"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestMigrationWaitsForTheMaintenanceWindow(t *testing.T) {
+	now := time.Date(2018, 3, 1, 12, 0, 0, 0, time.UTC)
+	poolNode := func(name, pool string) corev1.Node {
+		node := taintedNode(name)
+		node.Labels = map[string]string{"pool": pool}
+		node.CreationTimestamp = metav1.NewTime(now.Add(-24 * time.Hour))
+		node.Status = corev1.NodeStatus{
+			Allocatable: corev1.ResourceList{corev1.ResourcePods: *resource.NewQuantity(10, resource.DecimalSI)},
+			Conditions:  []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+		}
+		return node
+	}
+	var pods []corev1.Pod
+	for _, name := range []string{"web-0", "web-1"} {
+		pods = append(pods, corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, GenerateName: "web-", Namespace: "default", UID: types.UID(name), CreationTimestamp: metav1.NewTime(now.Add(-time.Hour))},
+			Spec:       corev1.PodSpec{NodeName: "old-0"},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning, ContainerStatuses: []corev1.ContainerStatus{{Name: "main", Ready: true}}},
+		})
+	}
+	r, memory := testRescheduler([]corev1.Node{poolNode("old-0", "old"), poolNode("new-0", "new")}, pods)
+	r.now = func() time.Time { return now }
+	r.defaultPolicy.MaintenanceWindows.Windows = []policy.MaintenanceWindow{{Start: "01:00", End: "05:00"}}
+	if err := r.defaultPolicy.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	r.ctx = ctx
+
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+	if r.auditLog, err = audit.NewLog(path, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	source, target := *sourceNodeSelector, *targetNodeSelector
+	defer func() { *sourceNodeSelector, *targetNodeSelector = source, target }()
+	*sourceNodeSelector, *targetNodeSelector = "pool=old", "pool=new"
+
+	if code := migrateCommand(r, nil); code != 1 {
+		t.Errorf("expected the interrupted migration to fail, got exit code %d", code)
+	}
+	r.auditLog.Close()
+	if got := memory.Pods(); len(got) != len(pods) {
+		t.Errorf("expected no eviction outside the maintenance window, got: %v", got)
+	}
+	if got := nodeTaints(t, memory)["old-0"]; got != "[]" {
+		t.Errorf("expected the source node not to be steered, got: %s", got)
+	}
+	var out strings.Builder
+	if err := audit.Query(path, 0, &audit.Filter{}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `"decision":"deferred"`) || !strings.Contains(out.String(), engine.ReasonOutsideWindow) {
+		t.Errorf("expected the move to be deferred until the maintenance window, got: %s", out.String())
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/hortonworks/pod-rescheduler/notify"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// headerFlag collects the repeated Name: value flags into HTTP headers
+type headerFlag map[string]string
+
+func newHeaderFlag(name, usage string) headerFlag {
+	headers := make(headerFlag)
+	flag.Var(headers, name, usage)
+	return headers
+}
+
+func (h headerFlag) String() string {
+	var headers []string
+	for name := range h {
+		headers = append(headers, name)
+	}
+	return strings.Join(headers, ", ")
+}
+
+func (h headerFlag) Set(value string) error {
+	parts := strings.SplitN(value, ":", 2)
+	if len(parts) != 2 || len(strings.TrimSpace(parts[0])) == 0 {
+		return fmt.Errorf("%q is not a Name: value header", value)
+	}
+	h[http.CanonicalHeaderKey(strings.TrimSpace(parts[0]))] = strings.TrimSpace(parts[1])
+	return nil
+}
+
+// The notifier of the webhook given by the flags, nil when no webhook is given
+func newNotifier() (*notify.Notifier, error) {
+	if len(*webhookURL) == 0 {
+		return nil, nil
+	}
+	if u, err := url.Parse(*webhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("invalid webhook URL: %s, it must be an http or https URL", *webhookURL)
+	}
+	filter := notify.Filter{
+		Namespaces:  splitList(*webhookNamespaces),
+		Strategies:  splitList(*webhookStrategies),
+		MinSeverity: *webhookMinSeverity,
+	}
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	if *notifyBatchSize < 1 || *notifyBatchInterval <= 0 || *notifyRetries < 0 {
+		return nil, fmt.Errorf("invalid notification batching: %d notifications, %v interval and %d retries, the size and the interval must be positive",
+			*notifyBatchSize, *notifyBatchInterval, *notifyRetries)
+	}
+	if *notifyRepeatInterval < 0 {
+		return nil, fmt.Errorf("invalid notification repeat interval: %v, it must not be negative", *notifyRepeatInterval)
+	}
+	n := notify.New(notify.Options{
+		BatchSize:      *notifyBatchSize,
+		BatchInterval:  *notifyBatchInterval,
+		Retries:        *notifyRetries,
+		Backoff:        time.Second,
+		Timeout:        10 * time.Second,
+		RepeatInterval: *notifyRepeatInterval,
+	})
+	n.AddSink(&notify.Webhook{URL: *webhookURL, Headers: webhookHeaders}, filter)
+	return n, nil
+}
+
+// Comma separated values without the empty ones
+func splitList(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); len(v) > 0 {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// Send the notification if a notifier is configured, the cycle and the Pod are filled in
+func (r *rescheduler) notify(n notify.Notification, pod *corev1.Pod) {
+	if r.notifier == nil {
+		return
+	}
+	if len(n.CycleID) == 0 {
+		n.CycleID = r.cycleID
+	}
+	if pod != nil {
+		n.Namespace = pod.Namespace
+		n.Pod = pod.Name
+		n.SourceNode = pod.Spec.NodeName
+	}
+	n.Timestamp = r.now()
+	r.notifier.Notify(n)
+}
+
+// Deliver the queued notifications before the process exits
+func (r *rescheduler) closeNotifier() {
+	if r.notifier == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	r.notifier.Close(ctx)
+}
@@ -0,0 +1,255 @@
+// Package notify delivers the notable decisions and failures of the rescheduler to external systems, e.g. a webhook of an on-call channel.
+// The notifications are batched and delivered in the background, so the housekeeping cycles do not wait for the sinks
+package notify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// Severities of the notifications, from the lowest to the highest
+const (
+	Info    = "info"
+	Warning = "warning"
+	Error   = "error"
+)
+
+var severities = map[string]int{Info: 0, Warning: 1, Error: 2}
+
+// Kinds of the notifications
+const (
+	Evicted          = "evicted"
+	EvictionFailed   = "eviction_failed"
+	Skipped          = "skipped"
+	ReadinessTimeout = "readiness_timeout"
+	CycleFailed      = "cycle_failed"
+)
+
+// The waits between the retries of a batch are up to this much longer than the backoff
+const retryJitter = 0.5
+
+// Notification is a decision or a failure of the rescheduler
+type Notification struct {
+	Timestamp  time.Time `json:"timestamp"`
+	Kind       string    `json:"kind"`
+	Severity   string    `json:"severity"`
+	CycleID    string    `json:"cycleId,omitempty"`
+	Strategy   string    `json:"strategy,omitempty"`
+	Group      string    `json:"group,omitempty"`
+	Namespace  string    `json:"namespace,omitempty"`
+	Pod        string    `json:"pod,omitempty"`
+	SourceNode string    `json:"sourceNode,omitempty"`
+	TargetNode string    `json:"targetNode,omitempty"`
+	Reason     string    `json:"reason,omitempty"`
+	Message    string    `json:"message"`
+}
+
+// Sink delivers a batch of notifications. A failed batch is sent again, so the sink must not keep a partial delivery
+type Sink interface {
+	Name() string
+	Send(ctx context.Context, batch []Notification) error
+}
+
+// Filter selects the notifications delivered to a sink, an empty list matches every namespace or strategy
+type Filter struct {
+	Namespaces  []string
+	Strategies  []string
+	MinSeverity string
+}
+
+// Validate checks the minimum severity, empty means every severity
+func (f *Filter) Validate() error {
+	if _, known := severities[f.MinSeverity]; !known && len(f.MinSeverity) > 0 {
+		return fmt.Errorf("unknown severity: %s, it must be info, warning or error", f.MinSeverity)
+	}
+	return nil
+}
+
+// Matches tells whether the notification passes the filter. The notifications without a namespace or a strategy,
+// e.g. the failed cycles, pass the namespace and the strategy filters
+func (f *Filter) Matches(n *Notification) bool {
+	return severities[n.Severity] >= severities[f.MinSeverity] &&
+		(len(n.Namespace) == 0 || contains(f.Namespaces, n.Namespace)) &&
+		(len(n.Strategy) == 0 || contains(f.Strategies, n.Strategy))
+}
+
+func contains(values []string, value string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Options of the delivery shared by the sinks
+type Options struct {
+	// BatchSize is the most notifications in a batch, a full batch is sent without waiting for the interval
+	BatchSize     int
+	BatchInterval time.Duration
+	// Retries of a failed batch, the backoff doubles after every retry
+	Retries int
+	Backoff time.Duration
+	// Timeout of sending a batch to a sink
+	Timeout time.Duration
+	// RepeatInterval is how long the same notification is not sent again, e.g. a group which cannot be moved in every cycle
+	RepeatInterval time.Duration
+}
+
+type filteredSink struct {
+	sink   Sink
+	filter Filter
+}
+
+// Notifier batches the notifications and delivers them to the sinks whose filter they match
+type Notifier struct {
+	options Options
+	sinks   []filteredSink
+	queue   chan Notification
+	// when a notification was queued last time, by its key
+	sent map[string]time.Time
+	// the queue is closed, guarded by the mutex as well, so no notification is queued after it
+	closed  bool
+	mutex   sync.Mutex
+	stopped chan struct{}
+}
+
+// New starts a notifier without sinks, AddSink has to be called before the first notification
+func New(options Options) *Notifier {
+	n := &Notifier{
+		options: options,
+		queue:   make(chan Notification, 10*options.BatchSize),
+		sent:    make(map[string]time.Time),
+		stopped: make(chan struct{}),
+	}
+	go n.deliver()
+	return n
+}
+
+// AddSink delivers the notifications matching the filter to the sink
+func (n *Notifier) AddSink(sink Sink, filter Filter) {
+	n.sinks = append(n.sinks, filteredSink{sink: sink, filter: filter})
+}
+
+// Notify queues the notification unless the same one was queued within the repeat interval.
+// The notification is dropped when the queue is full, the cycles are never blocked by a slow sink.
+// It is dropped after Close as well, e.g. when a readiness wait ends while the rescheduler stops
+func (n *Notifier) Notify(notification Notification) {
+	if notification.Timestamp.IsZero() {
+		notification.Timestamp = time.Now()
+	}
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if n.closed {
+		log.WithField("kind", notification.Kind).Warn("Notifier is stopped, dropping the notification")
+		return
+	}
+	if !n.firstWithinRepeatInterval(&notification) {
+		log.WithField("kind", notification.Kind).Debug("Notification was already sent")
+		return
+	}
+	select {
+	case n.queue <- notification:
+	default:
+		log.WithField("kind", notification.Kind).Warn("Notification queue is full, dropping the notification")
+	}
+}
+
+// Called with the mutex held
+func (n *Notifier) firstWithinRepeatInterval(notification *Notification) bool {
+	key := strings.Join([]string{notification.Kind, notification.Namespace, notification.Group, notification.Pod, notification.Reason, notification.Message}, "/")
+	if last, found := n.sent[key]; found && notification.Timestamp.Sub(last) < n.options.RepeatInterval {
+		return false
+	}
+	n.sent[key] = notification.Timestamp
+	for k, last := range n.sent {
+		if notification.Timestamp.Sub(last) >= n.options.RepeatInterval {
+			delete(n.sent, k)
+		}
+	}
+	return true
+}
+
+// Close delivers the queued notifications and stops the notifier, it gives up when the context is done
+func (n *Notifier) Close(ctx context.Context) {
+	n.mutex.Lock()
+	if !n.closed {
+		n.closed = true
+		close(n.queue)
+	}
+	n.mutex.Unlock()
+	select {
+	case <-n.stopped:
+	case <-ctx.Done():
+		log.Warn("Could not deliver every notification before stopping")
+	}
+}
+
+// Collect the notifications into batches until the queue is closed, a batch is sent when it is full or the interval passes
+func (n *Notifier) deliver() {
+	defer close(n.stopped)
+	ticker := time.NewTicker(n.options.BatchInterval)
+	defer ticker.Stop()
+	var batch []Notification
+	for {
+		select {
+		case notification, open := <-n.queue:
+			if !open {
+				n.send(batch)
+				return
+			}
+			if batch = append(batch, notification); len(batch) >= n.options.BatchSize {
+				n.send(batch)
+				batch = nil
+			}
+		case <-ticker.C:
+			n.send(batch)
+			batch = nil
+		}
+	}
+}
+
+func (n *Notifier) send(batch []Notification) {
+	for _, s := range n.sinks {
+		var selected []Notification
+		for i := range batch {
+			if s.filter.Matches(&batch[i]) {
+				selected = append(selected, batch[i])
+			}
+		}
+		if len(selected) > 0 {
+			n.sendWithRetries(s.sink, selected)
+		}
+	}
+}
+
+// Send the batch to the sink, failed sends are retried with an exponential backoff and jitter
+func (n *Notifier) sendWithRetries(sink Sink, batch []Notification) {
+	backoff := n.options.Backoff
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), n.options.Timeout)
+		err := sink.Send(ctx, batch)
+		cancel()
+		if err == nil {
+			return
+		}
+		entry := log.WithFields(log.Fields{"sink": sink.Name(), "notifications": len(batch)})
+		if attempt >= n.options.Retries {
+			entry.Errorf("Failed to send notifications, dropping them: %s", err.Error())
+			return
+		}
+		delay := wait.Jitter(backoff, retryJitter)
+		entry.Warningf("Failed to send notifications, retrying in %v: %s", delay.Truncate(time.Millisecond), err.Error())
+		time.Sleep(delay)
+		backoff *= 2
+	}
+}
@@ -0,0 +1,76 @@
+package notify
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testSink records the delivered notifications
+type testSink struct {
+	mutex     sync.Mutex
+	delivered []Notification
+}
+
+func (s *testSink) Name() string {
+	return "test"
+}
+
+func (s *testSink) Send(ctx context.Context, batch []Notification) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.delivered = append(s.delivered, batch...)
+	return nil
+}
+
+func testNotifier(sink Sink) *Notifier {
+	n := New(Options{BatchSize: 10, BatchInterval: time.Hour, Timeout: time.Second, RepeatInterval: time.Minute})
+	n.AddSink(sink, Filter{})
+	return n
+}
+
+func TestNotifierSendsTheSameNotificationOncePerRepeatInterval(t *testing.T) {
+	sink := &testSink{}
+	n := testNotifier(sink)
+	now := time.Date(2018, 3, 1, 12, 0, 0, 0, time.UTC)
+	for _, at := range []time.Duration{0, 30 * time.Second, time.Minute} {
+		n.Notify(Notification{Timestamp: now.Add(at), Kind: Skipped, Group: "default/web", Message: "no target node"})
+	}
+	n.Notify(Notification{Timestamp: now, Kind: Evicted, Group: "default/web", Message: "moved"})
+	n.Close(context.Background())
+
+	var kinds []string
+	for _, delivered := range sink.delivered {
+		kinds = append(kinds, delivered.Kind+" "+delivered.Timestamp.Sub(now).String())
+	}
+	if want := []string{"skipped 0s", "skipped 1m0s", "evicted 0s"}; len(kinds) != len(want) ||
+		kinds[0] != want[0] || kinds[1] != want[1] || kinds[2] != want[2] {
+		t.Errorf("unexpected notifications: %v, want %v", kinds, want)
+	}
+}
+
+func TestNotifierDropsNotificationsAfterClose(t *testing.T) {
+	sink := &testSink{}
+	n := testNotifier(sink)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				n.Notify(Notification{Kind: ReadinessTimeout, Message: "not ready"})
+			}
+		}()
+	}
+	n.Close(context.Background())
+	n.Close(context.Background())
+	wg.Wait()
+	n.Notify(Notification{Kind: CycleFailed, Message: "failed"})
+
+	for _, delivered := range sink.delivered {
+		if delivered.Kind == CycleFailed {
+			t.Errorf("a notification was delivered after Close: %v", delivered)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// Webhook posts the batches as a JSON object to a URL: {"notifications": [...]}
+type Webhook struct {
+	URL     string
+	Headers map[string]string
+	Client  *http.Client
+}
+
+type webhookPayload struct {
+	Notifications []Notification `json:"notifications"`
+}
+
+// Name of the sink in the logs
+func (w *Webhook) Name() string {
+	return "webhook"
+}
+
+// Send posts the batch, a response other than 2xx is an error
+func (w *Webhook) Send(ctx context.Context, batch []Notification) error {
+	body, err := json.Marshal(webhookPayload{Notifications: batch})
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	for name, value := range w.Headers {
+		request.Header.Set(name, value)
+	}
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	response, err := client.Do(request.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	// drain the body, so the connection is reused
+	io.Copy(ioutil.Discard, io.LimitReader(response.Body, 64*1024))
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("webhook responded %s", response.Status)
+	}
+	return nil
+}
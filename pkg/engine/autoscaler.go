@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Annotations and taints of the cluster-autoscaler
+const (
+	// SafeToEvictAnnotation set to "false" on a Pod protects it from the evictions
+	SafeToEvictAnnotation = "cluster-autoscaler.kubernetes.io/safe-to-evict"
+	// ScaleDownDisabledAnnotation set to "true" on a node keeps it out of the targets
+	ScaleDownDisabledAnnotation = "cluster-autoscaler.kubernetes.io/scale-down-disabled"
+	// ToBeDeletedTaint is put on the nodes the autoscaler is removing
+	ToBeDeletedTaint = "ToBeDeletedByClusterAutoscaler"
+)
+
+// ReasonNotSafeToEvict is the reason of not moving a Pod the cluster-autoscaler must not evict either
+const ReasonNotSafeToEvict = "safe_to_evict"
+
+// The cluster-autoscaler does not allow to evict the Pod
+func notSafeToEvict(pod *corev1.Pod) bool {
+	return pod.Annotations[SafeToEvictAnnotation] == "false"
+}
+
+// The nodes which the cluster-autoscaler is removing or which are annotated to stay out of the scale down, they are not targets of any move
+func autoscalerNotTargets(nodes []corev1.Node, notTargets map[string]bool) {
+	for _, node := range nodes {
+		if node.Annotations[ScaleDownDisabledAnnotation] == "true" {
+			notTargets[node.Name] = true
+		}
+		for _, taint := range node.Spec.Taints {
+			if taint.Key == ToBeDeletedTaint {
+				notTargets[node.Name] = true
+			}
+		}
+	}
+}
+
+// The nodes created within the maximum age, all of them if none of them is new enough or the new nodes are not preferred
+func newNodes(nodes []*corev1.Node, maxAge time.Duration, now time.Time) []*corev1.Node {
+	if maxAge <= 0 {
+		return nodes
+	}
+	var young []*corev1.Node
+	for _, node := range nodes {
+		if now.Sub(node.CreationTimestamp.Time) < maxAge {
+			young = append(young, node)
+		}
+	}
+	if len(young) == 0 {
+		return nodes
+	}
+	return young
+}
+
+// The age of the target node and the time it became Ready, the inputs of a move which show whether the target is a new node
+func targetInputs(node *corev1.Node, now time.Time, inputs map[string]string) {
+	if !node.CreationTimestamp.IsZero() {
+		inputs["targetAge"] = now.Sub(node.CreationTimestamp.Time).Truncate(time.Second).String()
+	}
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady && condition.Status == corev1.ConditionTrue {
+			inputs["targetReadySince"] = condition.LastTransitionTime.UTC().Format(time.RFC3339)
+		}
+	}
+}
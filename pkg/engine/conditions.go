@@ -0,0 +1,201 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/hortonworks/pod-rescheduler/policy"
+	utils "github.com/hortonworks/pod-rescheduler/utils"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// A Pod on a node with a bad condition
+type evacuee struct {
+	pod       *corev1.Pod
+	group     string
+	condition corev1.NodeCondition
+}
+
+// Move the Pods off the nodes whose configured condition is True for at least its minimum duration.
+// The BestEffort Pods are moved first, then the Burstable and finally the Guaranteed ones, as the kubelet would evict them.
+// The minimum replica count is not checked, because the kubelet does not check it either
+func (pl *planner) evacuate() {
+	strategy := &pl.policy.Strategies.NodeConditions
+	var evacuees []evacuee
+	for _, node := range pl.snapshot.Nodes {
+		condition, since := badCondition(&node, strategy, pl.state.Now)
+		if condition == nil {
+			continue
+		}
+		if since < strategy.Condition(string(condition.Type)).MinDuration.Duration {
+			pl.deferred(NodeConditionsStrategy, "", nil, ReasonConditionNew, map[string]string{
+				"node":         node.Name,
+				"condition":    string(condition.Type),
+				"conditionFor": since.String(),
+				"minDuration":  strategy.Condition(string(condition.Type)).MinDuration.Duration.String(),
+			})
+			continue
+		}
+		for _, pods := range [][]corev1.Pod{pl.snapshot.PodsPerNode[node.Name], pl.snapshot.PodsOnExcludedNodes[node.Name]} {
+			for i := range pods {
+				group := utils.GetPodGroupName(&pods[i])
+				if group == nil || !strategy.Namespaces.Contains(pods[i].Namespace) {
+					continue
+				}
+				evacuees = append(evacuees, evacuee{pod: &pods[i], group: *group, condition: *condition})
+			}
+		}
+	}
+	sort.SliceStable(evacuees, func(i, j int) bool {
+		qi, qj := qosRank(evacuees[i].pod), qosRank(evacuees[j].pod)
+		if qi != qj {
+			return qi < qj
+		}
+		return evacuees[i].pod.Name < evacuees[j].pod.Name
+	})
+
+	for _, e := range evacuees {
+		if e.pod.Status.Phase != corev1.PodRunning {
+			pl.skip(NodeConditionsStrategy, e.group, e.pod, ReasonNotReady, nil)
+			continue
+		}
+		if isDaemonSetPod(e.pod) {
+			pl.skip(NodeConditionsStrategy, e.group, e.pod, ReasonDaemonSet, nil)
+			continue
+		}
+		if !pl.groupMovable(NodeConditionsStrategy, e.group) {
+			continue
+		}
+		if reason := podNotEligible(e.pod, pl.policy, pl.state.Now); len(reason) > 0 {
+			pl.skip(NodeConditionsStrategy, e.group, e.pod, reason, nil)
+			continue
+		}
+		if pl.rateLimited(NodeConditionsStrategy, e.group, e.pod) {
+			continue
+		}
+		target, score := pl.healthyNode(e.group, e.pod)
+		if target == nil {
+			pl.skip(NodeConditionsStrategy, e.group, e.pod, ReasonNoTargetNode, nil)
+			continue
+		}
+		pl.move(Action{
+			Strategy: NodeConditionsStrategy,
+			Group:    e.group,
+			Pod:      e.pod,
+			Target:   target.Name,
+			Reason: fmt.Sprintf("node %s has condition %s since %s", e.pod.Spec.NodeName, e.condition.Type,
+				e.condition.LastTransitionTime.Format(time.RFC3339)),
+			Inputs: map[string]string{
+				"condition":   string(e.condition.Type),
+				"qosClass":    string(qosClass(e.pod)),
+				"targetScore": strconv.Itoa(score),
+			},
+		})
+	}
+}
+
+// A healthy node of the pool of the Pod which can run it and its score: the best scoring one without a Pod of the group,
+// otherwise the best scoring one of those with the fewest Pods of the group. The Pods moved to the nodes in the cycle count as well
+func (pl *planner) healthyNode(group string, pod *corev1.Pod) (*corev1.Node, int) {
+	var candidates []*corev1.Node
+	fewest := -1
+	for i := range pl.snapshot.Nodes {
+		node := &pl.snapshot.Nodes[i]
+		if pl.notTargets[node.Name] || !pl.canHost(node, pod) ||
+			(pl.pools != nil && pl.pools[node.Name] != pl.pools[pod.Spec.NodeName]) {
+			continue
+		}
+		count := countGroup(pl.snapshot.PodsPerNode[node.Name], group)
+		for _, moved := range pl.reserved[node.Name] {
+			if groupOf(moved) == group {
+				count++
+			}
+		}
+		if fewest < 0 || count < fewest {
+			candidates, fewest = nil, count
+		}
+		if count == fewest {
+			candidates = append(candidates, node)
+		}
+	}
+	c := pl.scoreContext(group, pod)
+	c.Candidates = candidates
+	return BestNode(c, &pl.policy.Scoring)
+}
+
+// The configured condition of the node which is True for the longest time and how long it is True
+func badCondition(node *corev1.Node, strategy *policy.NodeConditionsStrategy, now time.Time) (*corev1.NodeCondition, time.Duration) {
+	var bad *corev1.NodeCondition
+	for i, condition := range node.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue || strategy.Condition(string(condition.Type)) == nil {
+			continue
+		}
+		if bad == nil || condition.LastTransitionTime.Before(bad.LastTransitionTime) {
+			bad = &node.Status.Conditions[i]
+		}
+	}
+	if bad == nil {
+		return nil, 0
+	}
+	return bad, now.Sub(bad.LastTransitionTime.Time)
+}
+
+// The nodes with a configured condition which is True, they are not targets of any move
+func unhealthyNodes(nodes []corev1.Node, strategy *policy.NodeConditionsStrategy, now time.Time) map[string]bool {
+	unhealthy := make(map[string]bool)
+	if !strategy.Enabled {
+		return unhealthy
+	}
+	for i := range nodes {
+		if condition, _ := badCondition(&nodes[i], strategy, now); condition != nil {
+			unhealthy[nodes[i].Name] = true
+		}
+	}
+	return unhealthy
+}
+
+func isDaemonSetPod(pod *corev1.Pod) bool {
+	owner := metav1.GetControllerOf(pod)
+	return owner != nil && owner.Kind == "DaemonSet"
+}
+
+func qosRank(pod *corev1.Pod) int {
+	switch qosClass(pod) {
+	case corev1.PodQOSBestEffort:
+		return 0
+	case corev1.PodQOSBurstable:
+		return 1
+	}
+	return 2
+}
+
+// The QoS class of the Pod, computed from the resources of the containers if the status does not have it yet
+func qosClass(pod *corev1.Pod) corev1.PodQOSClass {
+	if len(pod.Status.QOSClass) > 0 {
+		return pod.Status.QOSClass
+	}
+	bestEffort, guaranteed := true, true
+	for _, container := range pod.Spec.Containers {
+		requests, limits := container.Resources.Requests, container.Resources.Limits
+		if len(requests) > 0 || len(limits) > 0 {
+			bestEffort = false
+		}
+		for _, resource := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+			limit, hasLimit := limits[resource]
+			request, hasRequest := requests[resource]
+			if !hasLimit || (hasRequest && request.Cmp(limit) != 0) {
+				guaranteed = false
+			}
+		}
+	}
+	if bestEffort {
+		return corev1.PodQOSBestEffort
+	}
+	if guaranteed {
+		return corev1.PodQOSGuaranteed
+	}
+	return corev1.PodQOSBurstable
+}
@@ -0,0 +1,164 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/hortonworks/pod-rescheduler/policy"
+	utils "github.com/hortonworks/pod-rescheduler/utils"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Strategies which move every Pod off some nodes on request
+const (
+	DrainStrategy   = "drain"
+	MigrateStrategy = "migrate"
+)
+
+// ReasonNoController is the reason of not moving a Pod which would not be recreated
+const ReasonNoController = "no_controller"
+
+// Drain plans the moves of the Pods off the node, the node itself is never a target
+func Drain(snapshot *Snapshot, state *State, node string, p *policy.Config) []Action {
+	pl := newPlanner(snapshot, state, p)
+	pl.relocate(DrainStrategy, []string{node}, "node %s is drained")
+	return pl.actions
+}
+
+// Migrate plans the moves of the Pods off the nodes of the source pool, only the nodes of the target pool are targets
+func Migrate(snapshot *Snapshot, state *State, source, target labels.Selector, p *policy.Config) []Action {
+	pl := newPlanner(snapshot, state, p)
+	// the Pods leave their pool, the target selector decides where they go
+	pl.pools = nil
+	var sources []string
+	for _, node := range snapshot.Nodes {
+		if source.Matches(labels.Set(node.Labels)) {
+			sources = append(sources, node.Name)
+		}
+		if !target.Matches(labels.Set(node.Labels)) {
+			pl.notTargets[node.Name] = true
+		}
+	}
+	pl.relocate(MigrateStrategy, sources, "node %s is in the source pool")
+	return pl.actions
+}
+
+// A Pod to move off a drained node and the target reserved for it by the pre-validation
+type relocation struct {
+	pod    *corev1.Pod
+	group  string
+	target *corev1.Node
+	score  int
+}
+
+// Plan the moves of the Pods off the nodes ordered by their groups, the nodes themselves are never targets.
+// Only the Pods of the groups which are not in flight are moved, one Pod per group.
+// The Pods which cannot be moved are returned as skip actions, DaemonSet Pods are skipped quietly.
+// A target is reserved for every Pod of the nodes before the first move, so the nodes are not drained
+// when the other nodes cannot take all of their Pods: the Pods without a target are skipped and no move is planned
+func (pl *planner) relocate(strategy string, nodes []string, reason string) {
+	p := pl.policy
+	var pods []corev1.Pod
+	for _, node := range nodes {
+		pl.notTargets[node] = true
+		pods = append(pods, pl.snapshot.PodsPerNode[node]...)
+		pods = append(pods, pl.snapshot.PodsOnExcludedNodes[node]...)
+	}
+	sort.Slice(pods, func(i, j int) bool {
+		gi, gj := groupOf(&pods[i]), groupOf(&pods[j])
+		if gi != gj {
+			return gi < gj
+		}
+		return pods[i].Name < pods[j].Name
+	})
+	var relocations []relocation
+	for i := range pods {
+		pod := &pods[i]
+		group := groupOf(pod)
+		switch {
+		case pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed:
+			continue
+		case isDaemonSetPod(pod):
+			pl.skip(strategy, "", pod, ReasonDaemonSet, nil)
+			continue
+		case len(group) == 0:
+			pl.skip(strategy, "", pod, ReasonNoController, nil)
+			continue
+		}
+		if p.Eligibility.Excluded(group) {
+			pl.skip(strategy, group, pod, ReasonExcluded, nil)
+			continue
+		}
+		if notSafeToEvict(pod) {
+			pl.skip(strategy, group, pod, ReasonNotSafeToEvict, nil)
+			continue
+		}
+		relocations = append(relocations, relocation{pod: pod, group: group})
+	}
+
+	placed := true
+	for i := range relocations {
+		r := &relocations[i]
+		if r.target, r.score = pl.healthyNode(r.group, r.pod); r.target == nil {
+			pl.skip(strategy, r.group, r.pod, ReasonNoTargetNode, map[string]string{
+				"candidateNodes": strconv.Itoa(pl.candidateNodes()),
+				"podsToMove":     strconv.Itoa(len(relocations)),
+			})
+			placed = false
+			continue
+		}
+		pl.reserved[r.target.Name] = append(pl.reserved[r.target.Name], r.pod)
+	}
+	if !placed {
+		return
+	}
+	// the moves reserve their targets again
+	pl.reserved = make(map[string][]*corev1.Pod)
+
+	for _, r := range relocations {
+		if pl.state.InFlight[r.group] || pl.moved[r.group] {
+			pl.deferred(strategy, r.group, r.pod, ReasonInFlight, nil)
+			continue
+		}
+		if ready := pl.readyPods(r.group); ready < p.Strategies.Spread.MinReplicaCount {
+			pl.skip(strategy, r.group, r.pod, ReasonMinReplica, map[string]string{
+				"readyPods":       strconv.Itoa(ready),
+				"minReplicaCount": strconv.Itoa(p.Strategies.Spread.MinReplicaCount),
+			})
+			continue
+		}
+		pl.move(Action{
+			Strategy: strategy,
+			Group:    r.group,
+			Pod:      r.pod,
+			Target:   r.target.Name,
+			Reason:   fmt.Sprintf(reason, r.pod.Spec.NodeName),
+			Inputs:   map[string]string{"targetScore": strconv.Itoa(r.score)},
+		})
+	}
+}
+
+// The number of Running and Ready Pods of the group on every node, including the excluded ones
+func (pl *planner) readyPods(group string) int {
+	ready := 0
+	for _, podsPerNode := range []map[string][]corev1.Pod{pl.snapshot.PodsPerNode, pl.snapshot.PodsOnExcludedNodes} {
+		for _, pods := range podsPerNode {
+			for i := range pods {
+				if groupOf(&pods[i]) == group && pods[i].Status.Phase == corev1.PodRunning && IsPodReady(&pods[i]) {
+					ready++
+				}
+			}
+		}
+	}
+	return ready
+}
+
+// The group of the Pod, empty if it does not have one
+func groupOf(pod *corev1.Pod) string {
+	if group := utils.GetPodGroupName(pod); group != nil {
+		return *group
+	}
+	return ""
+}
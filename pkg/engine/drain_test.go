@@ -0,0 +1,135 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/hortonworks/pod-rescheduler/policy"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func ownedBy(pod corev1.Pod, kind string) corev1.Pod {
+	controller := true
+	pod.OwnerReferences = []metav1.OwnerReference{{Kind: kind, Name: "owner", Controller: &controller}}
+	return pod
+}
+
+func TestDrain(t *testing.T) {
+	single := testPod("single", 0, "n0")
+	single.GenerateName = ""
+	cases := []struct {
+		name     string
+		nodes    []corev1.Node
+		pods     []corev1.Pod
+		inFlight []string
+		policy   func(p *policy.Config)
+		want     []string
+	}{
+		{
+			name:  "a target is reserved for every Pod before the first move",
+			nodes: []corev1.Node{testNode("n0", 0), testNode("n1", 1)},
+			pods:  []corev1.Pod{testPod("g", 0, "n0"), testPod("g", 1, "n0"), testPod("g", 2, "n0")},
+			want: []string{
+				"skip drain default/g default/g-1 no_target_node",
+				"skip drain default/g default/g-2 no_target_node",
+			},
+		},
+		{
+			name:  "one Pod of the group is moved, the others wait for it",
+			nodes: []corev1.Node{testNode("n0", 0), testNode("n1", 3)},
+			pods:  []corev1.Pod{testPod("g", 0, "n0"), testPod("g", 1, "n0"), testPod("g", 2, "n0")},
+			want: []string{
+				"move drain default/g default/g-0 -> n1",
+				"defer drain default/g default/g-1 in_flight",
+				"defer drain default/g default/g-2 in_flight",
+			},
+		},
+		{
+			name:  "the Pods of a group are spread over the targets",
+			nodes: []corev1.Node{testNode("n0", 0), testNode("n1", 0), testNode("n2", 0)},
+			pods:  []corev1.Pod{testPod("a", 0, "n0"), testPod("b", 0, "n0"), testPod("b", 1, "n1"), testPod("b", 2, "n2")},
+			want: []string{
+				"move drain default/a default/a-0 -> n1",
+				"move drain default/b default/b-0 -> n1",
+			},
+		},
+		{
+			name:  "the Pods of the other groups count in the capacity",
+			nodes: []corev1.Node{testNode("n0", 0), testNode("n1", 2)},
+			pods:  []corev1.Pod{testPod("a", 0, "n0"), testPod("b", 0, "n0"), testPod("c", 0, "n1")},
+			want: []string{
+				"skip drain default/b default/b-0 no_target_node",
+			},
+		},
+		{
+			name:     "a group in flight is deferred",
+			nodes:    []corev1.Node{testNode("n0", 0), testNode("n1", 0)},
+			pods:     []corev1.Pod{testPod("a", 0, "n0"), testPod("a", 1, "n1")},
+			inFlight: []string{"default/a"},
+			want:     []string{"defer drain default/a default/a-0 in_flight"},
+		},
+		{
+			name:   "the minimum replica count is kept",
+			nodes:  []corev1.Node{testNode("n0", 0), testNode("n1", 0)},
+			pods:   []corev1.Pod{testPod("a", 0, "n0"), notReady(testPod("a", 1, "n1"))},
+			policy: func(p *policy.Config) { p.Strategies.Spread.MinReplicaCount = 2 },
+			want:   []string{"skip drain default/a default/a-0 min_replica_count"},
+		},
+		{
+			name:  "the Pods which would not be recreated are not moved",
+			nodes: []corev1.Node{testNode("n0", 0), testNode("n1", 0)},
+			pods: []corev1.Pod{
+				ownedBy(testPod("agent", 0, "n0"), "DaemonSet"), single, testPod("db", 0, "n0"), testPod("db", 1, "n1"),
+			},
+			policy: func(p *policy.Config) { p.Eligibility.ExcludeGroups = []string{"default/db"} },
+			want: []string{
+				"skip drain  default/single-0 no_controller",
+				"skip drain  default/agent-0 daemonset",
+				"skip drain default/db default/db-0 excluded",
+			},
+		},
+		{
+			name:  "a cordoned node is drained",
+			nodes: []corev1.Node{cordoned(testNode("n0", 0)), testNode("n1", 0)},
+			pods:  []corev1.Pod{testPod("a", 0, "n0"), testPod("a", 1, "n1")},
+			want:  []string{"move drain default/a default/a-0 -> n1"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p := testPolicy(t, func(p *policy.Config) {
+				p.Strategies.Spread.MinReplicaCount = 1
+				if c.policy != nil {
+					c.policy(p)
+				}
+			})
+			state := testState()
+			for _, group := range c.inFlight {
+				state.InFlight[group] = true
+			}
+			equalActions(t, Drain(observe(t, c.nodes, c.pods, p), state, "n0", p), c.want)
+		})
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	p := testPolicy(t, func(p *policy.Config) { p.Strategies.Spread.MinReplicaCount = 1 })
+	pool := func(node corev1.Node, name string) corev1.Node {
+		node.Labels["pool"] = name
+		return node
+	}
+	nodes := []corev1.Node{pool(testNode("old-0", 0), "old"), pool(testNode("old-1", 0), "old"), pool(testNode("new-0", 2), "new")}
+	pods := []corev1.Pod{testPod("a", 0, "old-0"), testPod("b", 0, "old-1")}
+	source, target := labels.SelectorFromSet(labels.Set{"pool": "old"}), labels.SelectorFromSet(labels.Set{"pool": "new"})
+
+	equalActions(t, Migrate(observe(t, nodes, pods, p), testState(), source, target, p), []string{
+		"move migrate default/a default/a-0 -> new-0",
+		"move migrate default/b default/b-0 -> new-0",
+	})
+
+	pods = append(pods, testPod("c", 0, "old-0"))
+	equalActions(t, Migrate(observe(t, nodes, pods, p), testState(), source, target, p), []string{
+		"skip migrate default/c default/c-0 no_target_node",
+	})
+}
@@ -0,0 +1,283 @@
+// Package engine computes the rescheduling decisions from a snapshot of the cluster and the policy.
+// Planning has no side effects: the Kubernetes API, the Events, the metrics and the logs are reached
+// only through the interfaces given to Observe and Execute
+package engine
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hortonworks/pod-rescheduler/policy"
+	utils "github.com/hortonworks/pod-rescheduler/utils"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Strategies of the actions
+const (
+	// SpreadStrategy moves Pods away from the nodes which run more than one Pod of the same group
+	SpreadStrategy = "spread"
+	// NodeConditionsStrategy moves Pods off the nodes which have a bad condition
+	NodeConditionsStrategy = "node_conditions"
+)
+
+// Types of the planned actions
+const (
+	ActionMove  = "move"
+	ActionSkip  = "skip"
+	ActionDefer = "defer"
+)
+
+// Reasons of not moving a Pod group or a Pod
+const (
+	ReasonExcluded      = "excluded"
+	ReasonInFlight      = "in_flight"
+	ReasonCooldown      = "cooldown"
+	ReasonRateLimit     = "rate_limit"
+	ReasonNoTargetNode  = "no_target_node"
+	ReasonMinReplica    = "min_replica_count"
+	ReasonNotSelected   = "pod_selector"
+	ReasonTooYoung      = "min_pod_age"
+	ReasonNotReady      = "not_ready"
+	ReasonBalanced      = "balanced"
+	ReasonAborted       = "aborted"
+	ReasonDaemonSet     = "daemonset"
+	ReasonConditionNew  = "condition_min_duration"
+	ReasonOutsideWindow = "maintenance_window"
+)
+
+// Snapshot is the state of the cluster observed at the beginning of a cycle
+type Snapshot struct {
+	Nodes []corev1.Node
+	// Pods of the namespace scope on the nodes which can run the moved Pods
+	PodsPerNode map[string][]corev1.Pod
+	// Pods of the namespace scope on the unschedulable or tainted nodes
+	PodsOnExcludedNodes map[string][]corev1.Pod
+	// Every Pod taking room on the nodes, of every namespace: the capacity and the scores of the nodes are computed from them
+	AllPodsPerNode map[string][]corev1.Pod
+	// The Pods of every namespace could not be listed, only the ones of the namespace scope take room on the nodes
+	ScopedPodsOnly bool
+}
+
+// Groups returns the Pods of the snapshot grouped by their Deployment/StatefulSet.
+// The Pods on the excluded nodes are members of their groups, they count in the minimum replica count
+func (s *Snapshot) Groups() map[string][]corev1.Pod {
+	var pods []corev1.Pod
+	for _, node := range s.Nodes {
+		pods = append(pods, s.PodsPerNode[node.Name]...)
+		pods = append(pods, s.PodsOnExcludedNodes[node.Name]...)
+	}
+	return GroupPods(pods)
+}
+
+// State is what the rescheduler remembers from the previous cycles
+type State struct {
+	// Groups with a Pod which is being rescheduled
+	InFlight map[string]bool
+	// When a Pod of the group was moved last time
+	LastMoves map[string]time.Time
+	// How many replacements of the group missed the target node recently, by target node
+	TargetMisses map[string]map[string]int
+	Now          time.Time
+}
+
+// InCooldown tells whether a Pod of the group was moved within the cooldown
+func (s *State) InCooldown(group string, cooldown time.Duration) bool {
+	last, ok := s.LastMoves[group]
+	return ok && s.Now.Sub(last) < cooldown
+}
+
+// Action is a planned move of a Pod or a skipped Pod group or Pod with the reason of the decision.
+// Inputs are the values the decision depends on
+type Action struct {
+	Type     string
+	Strategy string
+	Group    string
+	Pod      *corev1.Pod
+	Target   string
+	Reason   string
+	Inputs   map[string]string
+}
+
+// Quiet actions are part of the normal operation, they are not reported in the metrics and by default not in the audit log either
+func (a *Action) Quiet() bool {
+	return a.Type == ActionSkip && (a.Reason == ReasonBalanced || a.Reason == ReasonNotReady || a.Reason == ReasonDaemonSet)
+}
+
+// Plan returns the actions of a cycle. The Pods of the nodes with bad conditions are moved first,
+// then the groups are spread in the order of their names. A group is moved at most once in a cycle
+func Plan(snapshot *Snapshot, state *State, p *policy.Config) []Action {
+	pl := newPlanner(snapshot, state, p)
+	if p.Strategies.NodeConditions.Enabled {
+		pl.evacuate()
+	}
+	if p.Strategies.Spread.Enabled {
+		pl.spread()
+	}
+	return pl.actions
+}
+
+// planner collects the actions of the strategies, the limits of the policy are shared by them
+type planner struct {
+	snapshot *Snapshot
+	state    *State
+	policy   *policy.Config
+	groups   map[string][]corev1.Pod
+	// nodes which must not be targets, e.g. because of a bad condition
+	notTargets map[string]bool
+	// pool of every node, nil when the nodes are not partitioned into pools
+	pools map[string]string
+	// groups which have a move in this cycle
+	moved map[string]bool
+	// Pods moved to the nodes in this cycle, by target node
+	reserved map[string][]*corev1.Pod
+	moves    int
+	actions  []Action
+}
+
+func newPlanner(snapshot *Snapshot, state *State, p *policy.Config) *planner {
+	pl := &planner{
+		snapshot:   snapshot,
+		state:      state,
+		policy:     p,
+		groups:     snapshot.Groups(),
+		notTargets: unhealthyNodes(snapshot.Nodes, &p.Strategies.NodeConditions, state.Now),
+		pools:      NodePools(snapshot.Nodes, p.NodePoolLabel),
+		moved:      make(map[string]bool),
+		reserved:   make(map[string][]*corev1.Pod),
+	}
+	autoscalerNotTargets(snapshot.Nodes, pl.notTargets)
+	return pl
+}
+
+// The scoring of the candidate target nodes of the Pod, without the candidates
+func (pl *planner) scoreContext(group string, pod *corev1.Pod) *ScoreContext {
+	c := &ScoreContext{
+		Snapshot: pl.snapshot,
+		Group:    group,
+		Pod:      pod,
+		Misses:   pl.state.TargetMisses[group],
+		Now:      pl.state.Now,
+	}
+	if pl.policy.Autoscaler.PreferNewNodes {
+		c.NewNodeMaxAge = pl.policy.Autoscaler.NewNodeMaxAge.Duration
+	}
+	return c
+}
+
+// The number of schedulable nodes which can be targets
+func (pl *planner) candidateNodes() int {
+	count := 0
+	for node := range pl.snapshot.PodsPerNode {
+		if !pl.notTargets[node] {
+			count++
+		}
+	}
+	return count
+}
+
+// The checks of the group shared by the strategies, the skip action is added if the group cannot be moved
+func (pl *planner) groupMovable(strategy, group string) bool {
+	if blocked := pl.groupBlocked(strategy, group); blocked != nil {
+		pl.actions = append(pl.actions, *blocked)
+		return false
+	}
+	return true
+}
+
+// The skip or defer action of the group if it cannot be moved, nil if it can
+func (pl *planner) groupBlocked(strategy, group string) *Action {
+	p := pl.policy
+	if p.Eligibility.Excluded(group) {
+		return &Action{Type: ActionSkip, Strategy: strategy, Group: group, Reason: ReasonExcluded}
+	}
+	if pl.state.InFlight[group] || pl.moved[group] {
+		return &Action{Type: ActionDefer, Strategy: strategy, Group: group, Reason: ReasonInFlight}
+	}
+	if pl.state.InCooldown(group, p.RateLimits.GroupCooldown.Duration) {
+		return &Action{Type: ActionDefer, Strategy: strategy, Group: group, Reason: ReasonCooldown, Inputs: map[string]string{
+			"lastMoved":     pl.state.LastMoves[group].Format(time.RFC3339),
+			"groupCooldown": p.RateLimits.GroupCooldown.Duration.String(),
+		}}
+	}
+	return nil
+}
+
+// Call the function with every index below n on the workers of the policy, at least one. The function must not change the planner
+func (pl *planner) parallel(n int, f func(i int)) {
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < n && (w == 0 || w < pl.policy.Workers); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				f(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
+
+// The eviction limit of the cycle is reached, the deferred action is added
+func (pl *planner) rateLimited(strategy, group string, pod *corev1.Pod) bool {
+	if limit := pl.policy.RateLimits.MaxEvictionsPerCycle; limit > 0 && pl.moves >= limit {
+		pl.deferred(strategy, group, pod, ReasonRateLimit, map[string]string{
+			"maxEvictionsPerCycle": strconv.Itoa(limit),
+		})
+		return true
+	}
+	return false
+}
+
+func (pl *planner) move(action Action) {
+	action.Type = ActionMove
+	if pl.pools != nil {
+		if action.Inputs == nil {
+			action.Inputs = make(map[string]string)
+		}
+		action.Inputs["pool"] = pl.pools[action.Pod.Spec.NodeName]
+	}
+	if target := pl.node(action.Target); target != nil {
+		if action.Inputs == nil {
+			action.Inputs = make(map[string]string)
+		}
+		targetInputs(target, pl.state.Now, action.Inputs)
+	}
+	pl.moves++
+	pl.moved[action.Group] = true
+	pl.reserved[action.Target] = append(pl.reserved[action.Target], action.Pod)
+	pl.actions = append(pl.actions, action)
+}
+
+func (pl *planner) node(name string) *corev1.Node {
+	for i := range pl.snapshot.Nodes {
+		if pl.snapshot.Nodes[i].Name == name {
+			return &pl.snapshot.Nodes[i]
+		}
+	}
+	return nil
+}
+
+func (pl *planner) skip(strategy, group string, pod *corev1.Pod, reason string, inputs map[string]string) {
+	pl.actions = append(pl.actions, Action{Type: ActionSkip, Strategy: strategy, Group: group, Pod: pod, Reason: reason, Inputs: inputs})
+}
+
+func (pl *planner) deferred(strategy, group string, pod *corev1.Pod, reason string, inputs map[string]string) {
+	pl.actions = append(pl.actions, Action{Type: ActionDefer, Strategy: strategy, Group: group, Pod: pod, Reason: reason, Inputs: inputs})
+}
+
+// GroupPods groups the Pods that belong to the same Deployment/StatefulSet, single Pods are ignored
+func GroupPods(pods []corev1.Pod) map[string][]corev1.Pod {
+	result := make(map[string][]corev1.Pod)
+	for _, pod := range pods {
+		if groupName := utils.GetPodGroupName(&pod); groupName != nil {
+			result[*groupName] = append(result[*groupName], pod)
+		}
+	}
+	return result
+}
@@ -0,0 +1,356 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hortonworks/pod-rescheduler/policy"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var testNow = time.Date(2018, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// testCluster serves the nodes and the Pods of a test, the evicted Pods are recorded.
+// The Pods are listed per namespace only when it is namespaced, like with a namespaced Role
+type testCluster struct {
+	nodes      []corev1.Node
+	pods       []corev1.Pod
+	deleted    []string
+	namespaced bool
+}
+
+func (c *testCluster) ListNodes(ctx context.Context) ([]corev1.Node, error) {
+	return c.nodes, nil
+}
+
+func (c *testCluster) ListPods(ctx context.Context, namespace, node string) ([]corev1.Pod, error) {
+	if c.namespaced && len(namespace) == 0 {
+		return nil, apierrors.NewForbidden(schema.GroupResource{Resource: "pods"}, "", fmt.Errorf("cannot list pods at the cluster scope"))
+	}
+	var pods []corev1.Pod
+	for _, pod := range c.pods {
+		if (len(namespace) == 0 || pod.Namespace == namespace) && (len(node) == 0 || pod.Spec.NodeName == node) {
+			pods = append(pods, pod)
+		}
+	}
+	return pods, nil
+}
+
+func (c *testCluster) DeletePod(ctx context.Context, namespace, name string) error {
+	c.deleted = append(c.deleted, namespace+"/"+name)
+	return nil
+}
+
+// A schedulable node running at most the given number of Pods, 0 means no limit
+func testNode(name string, podCapacity int64) corev1.Node {
+	node := corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{}, CreationTimestamp: metav1.NewTime(testNow.Add(-24 * time.Hour))},
+		Status: corev1.NodeStatus{
+			Allocatable: corev1.ResourceList{},
+			Conditions:  []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+		},
+	}
+	if podCapacity > 0 {
+		node.Status.Allocatable[corev1.ResourcePods] = *resource.NewQuantity(podCapacity, resource.DecimalSI)
+	}
+	return node
+}
+
+func cordoned(node corev1.Node) corev1.Node {
+	node.Spec.Unschedulable = true
+	return node
+}
+
+// A Running and Ready Pod of the group in the default namespace, its name is the group name and the index
+func testPod(group string, index int, node string) corev1.Pod {
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              fmt.Sprintf("%s-%d", group, index),
+			GenerateName:      group + "-",
+			Namespace:         "default",
+			UID:               types.UID(fmt.Sprintf("uid-%s-%d", group, index)),
+			CreationTimestamp: metav1.NewTime(testNow.Add(-time.Hour)),
+		},
+		Spec: corev1.PodSpec{NodeName: node},
+		Status: corev1.PodStatus{
+			Phase:             corev1.PodRunning,
+			ContainerStatuses: []corev1.ContainerStatus{{Name: "main", Ready: true}},
+		},
+	}
+}
+
+func inNamespace(pod corev1.Pod, namespace string) corev1.Pod {
+	pod.Namespace = namespace
+	return pod
+}
+
+func notReady(pod corev1.Pod) corev1.Pod {
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{{Name: "main", Ready: false}}
+	return pod
+}
+
+// The requests of the Pod, in millicores and bytes
+func requesting(pod corev1.Pod, cpu, memory int64) corev1.Pod {
+	pod.Spec.Containers = []corev1.Container{{Name: "main", Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+		corev1.ResourceCPU:    *resource.NewMilliQuantity(cpu, resource.DecimalSI),
+		corev1.ResourceMemory: *resource.NewQuantity(memory, resource.BinarySI),
+	}}}}
+	return pod
+}
+
+// The allocatable resources of the node, in millicores and bytes
+func allocatable(node corev1.Node, cpu, memory int64) corev1.Node {
+	node.Status.Allocatable[corev1.ResourceCPU] = *resource.NewMilliQuantity(cpu, resource.DecimalSI)
+	node.Status.Allocatable[corev1.ResourceMemory] = *resource.NewQuantity(memory, resource.BinarySI)
+	return node
+}
+
+// The validated policy of the tests: spread with a maximum skew of 1 and a minimum replica count of 2 in the default namespace
+func testPolicy(t *testing.T, change func(p *policy.Config)) *policy.Config {
+	p := &policy.Config{
+		HousekeepingInterval: metav1.Duration{Duration: time.Minute},
+		Namespaces:           policy.NamespaceScope{Include: []string{"default"}},
+		Workers:              2,
+		Strategies: policy.Strategies{
+			Spread: policy.SpreadStrategy{Enabled: true, MinReplicaCount: 2, MaxSkew: 1},
+		},
+	}
+	if change != nil {
+		change(p)
+	}
+	if err := p.Validate(); err != nil {
+		t.Fatalf("invalid test policy: %v", err)
+	}
+	return p
+}
+
+func testState() *State {
+	return &State{InFlight: map[string]bool{}, LastMoves: map[string]time.Time{}, Now: testNow}
+}
+
+// Observe the test cluster with the namespace scope of the policy
+func observe(t *testing.T, nodes []corev1.Node, pods []corev1.Pod, p *policy.Config) *Snapshot {
+	snapshot, err := Observe(context.Background(), &testCluster{nodes: nodes, pods: pods}, &p.Namespaces)
+	if err != nil {
+		t.Fatalf("observe failed: %v", err)
+	}
+	return snapshot
+}
+
+// The actions in a compact form: type strategy group [pod] [reason] [-> target], e.g. "move spread default/web default/web-0 -> n1"
+func describe(actions []Action) []string {
+	var described []string
+	for _, a := range actions {
+		parts := []string{a.Type, a.Strategy, a.Group}
+		if a.Pod != nil {
+			parts = append(parts, a.Pod.Namespace+"/"+a.Pod.Name)
+		}
+		if a.Type == ActionMove {
+			parts = append(parts, "->", a.Target)
+		} else {
+			parts = append(parts, a.Reason)
+		}
+		described = append(described, strings.Join(parts, " "))
+	}
+	return described
+}
+
+// The actions which are not quiet
+func loud(actions []Action) []Action {
+	var result []Action
+	for _, a := range actions {
+		if !a.Quiet() {
+			result = append(result, a)
+		}
+	}
+	return result
+}
+
+func equalActions(t *testing.T, got []Action, want []string) {
+	t.Helper()
+	described := describe(got)
+	if strings.Join(described, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected actions\ngot:\n  %s\nwant:\n  %s", strings.Join(described, "\n  "), strings.Join(want, "\n  "))
+	}
+}
+
+func TestObserve(t *testing.T) {
+	p := testPolicy(t, nil)
+	tainted := testNode("n2", 0)
+	tainted.Spec.Taints = []corev1.Taint{{Key: "dedicated", Effect: corev1.TaintEffectNoSchedule}}
+	steered := testNode("n3", 0)
+	steered.Spec.Taints = []corev1.Taint{{Key: SteeringTaintPrefix + "uid", Effect: corev1.TaintEffectPreferNoSchedule}}
+	nodes := []corev1.Node{testNode("n0", 0), cordoned(testNode("n1", 0)), tainted, steered}
+	pods := []corev1.Pod{
+		testPod("web", 0, "n0"), testPod("web", 1, "n1"), testPod("web", 2, "n2"), testPod("web", 3, "n3"),
+		inNamespace(testPod("other", 0, "n0"), "other"), testPod("pending", 0, ""), testPod("lost", 0, "gone"),
+	}
+	snapshot := observe(t, nodes, pods, p)
+	counts := map[string]int{}
+	for node, onNode := range snapshot.PodsPerNode {
+		counts["schedulable "+node] = len(onNode)
+	}
+	for node, onNode := range snapshot.PodsOnExcludedNodes {
+		counts["excluded "+node] = len(onNode)
+	}
+	want := map[string]int{"schedulable n0": 1, "excluded n1": 1, "excluded n2": 1, "schedulable n3": 1}
+	if fmt.Sprint(counts) != fmt.Sprint(want) {
+		t.Errorf("unexpected Pods per node: %v, want %v", counts, want)
+	}
+	if groups := snapshot.Groups(); len(groups["default/web"]) != 4 {
+		t.Errorf("every Pod of the group is expected in it, got: %d", len(groups["default/web"]))
+	}
+}
+
+func TestObserveWithANamespacedRole(t *testing.T) {
+	nodes := []corev1.Node{testNode("n0", 0)}
+	pods := []corev1.Pod{testPod("web", 0, "n0"), inNamespace(testPod("web", 0, "n0"), "team"), inNamespace(testPod("other", 0, "n0"), "other")}
+	cases := []struct {
+		name       string
+		scope      policy.NamespaceScope
+		namespaced bool
+		want       int
+		err        string
+	}{
+		{name: "every namespace is listed", scope: policy.NamespaceScope{Include: []string{"default"}}, want: 3},
+		{name: "the included namespaces are listed", scope: policy.NamespaceScope{Include: []string{"default", "team"}}, namespaced: true, want: 2},
+		{name: "the excluded namespaces are not listed", scope: policy.NamespaceScope{Include: []string{"default", "team"}, Exclude: []string{"team"}}, namespaced: true, want: 1},
+		{name: "every namespace is in the scope", namespaced: true, err: "ClusterRole"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			snapshot, err := Observe(context.Background(), &testCluster{nodes: nodes, pods: pods, namespaced: c.namespaced}, &c.scope)
+			if len(c.err) > 0 {
+				if err == nil || !strings.Contains(err.Error(), c.err) {
+					t.Fatalf("expected an error about %s, got: %v", c.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := len(snapshot.AllPodsPerNode["n0"]); got != c.want || snapshot.ScopedPodsOnly != c.namespaced {
+				t.Errorf("expected %d Pods taking room, scoped only %v, got %d, %v", c.want, c.namespaced, got, snapshot.ScopedPodsOnly)
+			}
+		})
+	}
+}
+
+func TestPlan(t *testing.T) {
+	inPool := func(node corev1.Node, pool string) corev1.Node {
+		node.Labels["pool"] = pool
+		return node
+	}
+	pressure := func(node corev1.Node, since time.Duration) corev1.Node {
+		node.Status.Conditions = append(node.Status.Conditions, corev1.NodeCondition{
+			Type: corev1.NodeMemoryPressure, Status: corev1.ConditionTrue, LastTransitionTime: metav1.NewTime(testNow.Add(-since)),
+		})
+		return node
+	}
+	conditions := func(p *policy.Config) {
+		p.Strategies.NodeConditions = policy.NodeConditionsStrategy{
+			Enabled:    true,
+			Conditions: []policy.NodeCondition{{Type: string(corev1.NodeMemoryPressure), MinDuration: metav1.Duration{Duration: 10 * time.Minute}}},
+		}
+	}
+	cases := []struct {
+		name   string
+		nodes  []corev1.Node
+		pods   []corev1.Pod
+		policy func(p *policy.Config)
+		state  func(s *State)
+		want   []string
+	}{
+		{
+			name:  "a Pod is moved from the most loaded node to the least loaded one",
+			nodes: []corev1.Node{testNode("n0", 0), testNode("n1", 0)},
+			pods:  []corev1.Pod{testPod("web", 0, "n0"), testPod("web", 1, "n0")},
+			want:  []string{"move spread default/web default/web-0 -> n1"},
+		},
+		{
+			name:  "a group within the maximum skew is balanced",
+			nodes: []corev1.Node{testNode("n0", 0), testNode("n1", 0)},
+			pods:  []corev1.Pod{testPod("web", 0, "n0"), testPod("web", 1, "n0"), testPod("web", 2, "n1")},
+			want:  []string{"skip spread default/web balanced"},
+		},
+		{
+			name:   "a node above the maximum Pods per node is unloaded",
+			nodes:  []corev1.Node{testNode("n0", 0), testNode("n1", 0), testNode("n2", 0)},
+			pods:   []corev1.Pod{testPod("web", 0, "n0"), testPod("web", 1, "n0"), testPod("web", 2, "n1")},
+			policy: func(p *policy.Config) { p.Strategies.Spread.MaxSkew, p.Strategies.Spread.MaxPodsPerNode = 2, 1 },
+			want:   []string{"move spread default/web default/web-0 -> n2"},
+		},
+		{
+			name:  "a group in flight is deferred",
+			nodes: []corev1.Node{testNode("n0", 0), testNode("n1", 0)},
+			pods:  []corev1.Pod{testPod("web", 0, "n0"), testPod("web", 1, "n0")},
+			state: func(s *State) { s.InFlight["default/web"] = true },
+			want:  []string{"defer spread default/web in_flight"},
+		},
+		{
+			name:   "a group moved within the cooldown is deferred",
+			nodes:  []corev1.Node{testNode("n0", 0), testNode("n1", 0)},
+			pods:   []corev1.Pod{testPod("web", 0, "n0"), testPod("web", 1, "n0")},
+			policy: func(p *policy.Config) { p.RateLimits.GroupCooldown = metav1.Duration{Duration: 5 * time.Minute} },
+			state:  func(s *State) { s.LastMoves["default/web"] = testNow.Add(-time.Minute) },
+			want:   []string{"defer spread default/web cooldown"},
+		},
+		{
+			name:  "the evictions of a cycle are limited",
+			nodes: []corev1.Node{testNode("n0", 0), testNode("n1", 0)},
+			pods: []corev1.Pod{
+				testPod("api", 0, "n0"), testPod("api", 1, "n0"), testPod("web", 0, "n0"), testPod("web", 1, "n0"),
+			},
+			policy: func(p *policy.Config) { p.RateLimits.MaxEvictionsPerCycle = 1 },
+			want: []string{
+				"move spread default/api default/api-0 -> n1",
+				"defer spread default/web default/web-0 rate_limit",
+			},
+		},
+		{
+			name:   "the Pods are moved off a node with a bad condition first",
+			nodes:  []corev1.Node{pressure(testNode("n0", 0), time.Hour), testNode("n1", 0), testNode("n2", 0)},
+			pods:   []corev1.Pod{testPod("web", 0, "n0"), testPod("web", 1, "n1")},
+			policy: conditions,
+			want: []string{
+				"move node_conditions default/web default/web-0 -> n2",
+				"defer spread default/web in_flight",
+			},
+		},
+		{
+			name:   "a new bad condition is waited for",
+			nodes:  []corev1.Node{pressure(testNode("n0", 0), time.Minute), testNode("n1", 0)},
+			pods:   []corev1.Pod{testPod("web", 0, "n0"), testPod("web", 1, "n1")},
+			policy: conditions,
+			want: []string{
+				"defer node_conditions  condition_min_duration",
+				"skip spread default/web balanced",
+			},
+		},
+		{
+			name:   "the Pods stay in the pool of their node",
+			nodes:  []corev1.Node{inPool(testNode("n0", 0), "a"), inPool(testNode("n1", 0), "b"), inPool(testNode("n2", 0), "a")},
+			pods:   []corev1.Pod{testPod("web", 0, "n0"), testPod("web", 1, "n0")},
+			policy: func(p *policy.Config) { p.NodePoolLabel = "pool" },
+			want:   []string{"move spread default/web default/web-0 -> n2"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p := testPolicy(t, c.policy)
+			state := testState()
+			if c.state != nil {
+				c.state(state)
+			}
+			equalActions(t, Plan(observe(t, c.nodes, c.pods, p), state, p), c.want)
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hortonworks/pod-rescheduler/policy"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Cluster is the access to the Kubernetes API the engine needs
+type Cluster interface {
+	ListNodes(ctx context.Context) ([]corev1.Node, error)
+	// Pods of the namespace running on the node, every namespace is listed when the namespace is empty
+	ListPods(ctx context.Context, namespace, node string) ([]corev1.Pod, error)
+	DeletePod(ctx context.Context, namespace, name string) error
+}
+
+// Reporter is notified about the executed decisions, e.g. to log them, post Events, record metrics or write an audit log
+type Reporter interface {
+	Skipped(action Action)
+	Evicted(action Action, err error)
+}
+
+// Preparer is a Reporter which prepares the evictions, e.g. steers the replacement away from the source node.
+// The Pod is evicted even if the preparation fails
+type Preparer interface {
+	PrepareEviction(ctx context.Context, action Action)
+}
+
+// SteeringTaintPrefix is the prefix of the keys of the transient PreferNoSchedule taints which steer the replacements
+// of the evicted Pods away from their source node. The nodes with these taints only are not excluded
+const SteeringTaintPrefix = "steering.pod-rescheduler.hortonworks.com/"
+
+// IsSteeringTaint tells whether the rescheduler put the taint on the node to steer a replacement
+func IsSteeringTaint(taint corev1.Taint) bool {
+	return strings.HasPrefix(taint.Key, SteeringTaintPrefix)
+}
+
+// The taints of the node other than the steering ones
+func excludingTaints(node *corev1.Node) int {
+	count := 0
+	for _, taint := range node.Spec.Taints {
+		if !IsSteeringTaint(taint) {
+			count++
+		}
+	}
+	return count
+}
+
+// Observe lists the nodes and the Pods on them. The Pods of every namespace are listed at once and bucketed by their node,
+// all of them take room on the nodes but only the ones of the namespace scope can be moved.
+// Listing every namespace needs a ClusterRole, with a namespaced Role the Pods of the included namespaces are listed instead.
+// The Pods on the unschedulable and tainted nodes are kept apart, they are not moved but they count in their groups.
+// The observation fails if the Pods cannot be listed, because a node which looks empty would be the best target
+// and the groups would look smaller than they are: no decision is made on incomplete data
+func Observe(ctx context.Context, c Cluster, scope *policy.NamespaceScope) (*Snapshot, error) {
+	nodes, err := c.ListNodes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("node list error: %s", err.Error())
+	}
+	pods, scopedOnly, err := listPods(ctx, c, scope)
+	if err != nil {
+		return nil, fmt.Errorf("pod list error: %s", err.Error())
+	}
+	snapshot := &Snapshot{
+		Nodes:               nodes,
+		ScopedPodsOnly:      scopedOnly,
+		PodsPerNode:         make(map[string][]corev1.Pod),
+		PodsOnExcludedNodes: make(map[string][]corev1.Pod),
+		AllPodsPerNode:      make(map[string][]corev1.Pod),
+	}
+	for _, node := range nodes {
+		// ignore tainted nodes for now..
+		if node.Spec.Unschedulable || excludingTaints(&node) > 0 {
+			snapshot.PodsOnExcludedNodes[node.Name] = []corev1.Pod{}
+		} else {
+			snapshot.PodsPerNode[node.Name] = []corev1.Pod{}
+		}
+	}
+	for _, pod := range pods {
+		// the Pods which are not scheduled yet or run on a node which is not listed are left out
+		_, schedulable := snapshot.PodsPerNode[pod.Spec.NodeName]
+		_, excluded := snapshot.PodsOnExcludedNodes[pod.Spec.NodeName]
+		if (schedulable || excluded) && pod.Status.Phase != corev1.PodSucceeded && pod.Status.Phase != corev1.PodFailed {
+			snapshot.AllPodsPerNode[pod.Spec.NodeName] = append(snapshot.AllPodsPerNode[pod.Spec.NodeName], pod)
+		}
+		if !scope.Contains(pod.Namespace) {
+			continue
+		}
+		if onNode, found := snapshot.PodsPerNode[pod.Spec.NodeName]; found {
+			snapshot.PodsPerNode[pod.Spec.NodeName] = append(onNode, pod)
+		} else if onNode, found := snapshot.PodsOnExcludedNodes[pod.Spec.NodeName]; found {
+			snapshot.PodsOnExcludedNodes[pod.Spec.NodeName] = append(onNode, pod)
+		}
+	}
+	return snapshot, nil
+}
+
+// The Pods of every namespace. When they cannot be listed for lack of permission and the scope includes namespaces,
+// the Pods of the included namespaces are listed one by one and true is returned: the other Pods are not seen
+func listPods(ctx context.Context, c Cluster, scope *policy.NamespaceScope) ([]corev1.Pod, bool, error) {
+	pods, err := c.ListPods(ctx, metav1.NamespaceAll, "")
+	if err == nil || !apierrors.IsForbidden(err) {
+		return pods, false, err
+	}
+	if len(scope.Include) == 0 {
+		return nil, false, fmt.Errorf("%s, the Pods of every namespace are listed with a ClusterRole unless the namespace scope includes namespaces", err.Error())
+	}
+	pods = nil
+	for _, namespace := range scope.Include {
+		if !scope.Contains(namespace) {
+			continue
+		}
+		inNamespace, err := c.ListPods(ctx, namespace, "")
+		if err != nil {
+			return nil, false, err
+		}
+		pods = append(pods, inNamespace...)
+	}
+	return pods, true, nil
+}
+
+// Execute evicts the Pods of the moves and reports every action.
+// Once the context is done the remaining moves are not started, they are reported as deferred.
+// The moves are deferred as well when the maintenance windows are closed at the time of the eviction,
+// so the moves left when a window closes during the cycle are dropped
+func Execute(ctx context.Context, actions []Action, c Cluster, reporter Reporter, p *policy.Config, now func() time.Time) {
+	for _, action := range actions {
+		if action.Type != ActionMove {
+			reporter.Skipped(action)
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			action.Type = ActionDefer
+			action.Reason = ReasonAborted
+			action.Inputs = map[string]string{"error": err.Error()}
+			reporter.Skipped(action)
+			continue
+		}
+		if t := now(); !EvictionAllowed(action, p, t) {
+			action.Type = ActionDefer
+			action.Reason = ReasonOutsideWindow
+			action.Inputs = map[string]string{"time": t.Format(time.RFC3339)}
+			reporter.Skipped(action)
+			continue
+		}
+		if preparer, ok := reporter.(Preparer); ok {
+			preparer.PrepareEviction(ctx, action)
+		}
+		reporter.Evicted(action, c.DeletePod(ctx, action.Pod.Namespace, action.Pod.Name))
+	}
+}
+
+// EvictionAllowed tells whether the global, the namespace and the strategy maintenance windows are all open at the given time
+func EvictionAllowed(action Action, p *policy.Config, now time.Time) bool {
+	windows := [][]policy.MaintenanceWindow{p.MaintenanceWindows.Windows, strategyWindows(action.Strategy, p)}
+	if action.Pod != nil {
+		windows = append(windows, p.MaintenanceWindows.Namespaces[action.Pod.Namespace])
+	}
+	for _, w := range windows {
+		if !policy.Open(w, now) {
+			return false
+		}
+	}
+	return true
+}
+
+func strategyWindows(strategy string, p *policy.Config) []policy.MaintenanceWindow {
+	switch strategy {
+	case SpreadStrategy:
+		return p.Strategies.Spread.MaintenanceWindows
+	case NodeConditionsStrategy:
+		return p.Strategies.NodeConditions.MaintenanceWindows
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package engine
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hortonworks/pod-rescheduler/policy"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// testReporter records the reported actions
+type testReporter struct {
+	reported []Action
+}
+
+func (r *testReporter) Skipped(action Action) {
+	r.reported = append(r.reported, action)
+}
+
+func (r *testReporter) Evicted(action Action, err error) {
+	r.reported = append(r.reported, action)
+}
+
+func TestEvictionAllowed(t *testing.T) {
+	window := func(start, end string) []policy.MaintenanceWindow {
+		return []policy.MaintenanceWindow{{Start: start, End: end}}
+	}
+	// testNow is 12:00
+	cases := []struct {
+		name    string
+		windows func(p *policy.Config)
+		allowed bool
+	}{
+		{name: "no windows", allowed: true},
+		{
+			name:    "global window is open",
+			windows: func(p *policy.Config) { p.MaintenanceWindows.Windows = window("11:00", "13:00") },
+			allowed: true,
+		},
+		{
+			name:    "global window is closed",
+			windows: func(p *policy.Config) { p.MaintenanceWindows.Windows = window("01:00", "05:00") },
+		},
+		{
+			name: "window of the namespace is closed",
+			windows: func(p *policy.Config) {
+				p.MaintenanceWindows.Windows = window("11:00", "13:00")
+				p.MaintenanceWindows.Namespaces = map[string][]policy.MaintenanceWindow{"default": window("01:00", "05:00")}
+			},
+		},
+		{
+			name: "window of another namespace is closed",
+			windows: func(p *policy.Config) {
+				p.MaintenanceWindows.Namespaces = map[string][]policy.MaintenanceWindow{"other": window("01:00", "05:00")}
+			},
+			allowed: true,
+		},
+		{
+			name:    "window of the strategy is closed",
+			windows: func(p *policy.Config) { p.Strategies.Spread.MaintenanceWindows = window("01:00", "05:00") },
+		},
+		{
+			name:    "window of another strategy is closed",
+			windows: func(p *policy.Config) { p.Strategies.NodeConditions.MaintenanceWindows = window("01:00", "05:00") },
+			allowed: true,
+		},
+	}
+	pod := testPod("web", 0, "n0")
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p := testPolicy(t, c.windows)
+			action := Action{Type: ActionMove, Strategy: SpreadStrategy, Group: "default/web", Pod: &pod, Target: "n1"}
+			if allowed := EvictionAllowed(action, p, testNow); allowed != c.allowed {
+				t.Errorf("expected the eviction allowed %v, got %v", c.allowed, allowed)
+			}
+		})
+	}
+}
+
+func TestExecuteDropsTheMovesWhenTheWindowCloses(t *testing.T) {
+	p := testPolicy(t, func(p *policy.Config) {
+		p.MaintenanceWindows.Windows = []policy.MaintenanceWindow{{Start: "11:00", End: "12:01"}}
+	})
+	pods := []corev1.Pod{testPod("a", 0, "n0"), testPod("b", 0, "n0"), testPod("c", 0, "n0")}
+	var actions []Action
+	for i := range pods {
+		actions = append(actions, Action{Type: ActionMove, Strategy: SpreadStrategy, Group: groupOf(&pods[i]), Pod: &pods[i], Target: "n1"})
+	}
+	actions = append(actions, Action{Type: ActionSkip, Strategy: SpreadStrategy, Group: "default/d", Reason: ReasonBalanced})
+	// every eviction takes a minute, the window closes after the first one
+	clock := testNow
+	now := func() time.Time {
+		defer func() { clock = clock.Add(time.Minute) }()
+		return clock
+	}
+	cluster := &testCluster{}
+	reporter := &testReporter{}
+	Execute(context.Background(), actions, cluster, reporter, p, now)
+
+	if strings.Join(cluster.deleted, ",") != "default/a-0" {
+		t.Errorf("expected only the first Pod to be evicted, got: %v", cluster.deleted)
+	}
+	equalActions(t, reporter.reported, []string{
+		"move spread default/a default/a-0 -> n1",
+		"defer spread default/b default/b-0 maintenance_window",
+		"defer spread default/c default/c-0 maintenance_window",
+		"skip spread default/d balanced",
+	})
+}
@@ -0,0 +1,42 @@
+package engine
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// CanHost tells whether the node can run the Pod: it is schedulable and untainted, it matches the node selector of the Pod,
+// it has room for one more Pod and the requests of the Pod fit into its allocatable resources next to every Pod running on it
+func CanHost(snapshot *Snapshot, node *corev1.Node, pod *corev1.Pod) bool {
+	return canHost(snapshot, node, pod, nil)
+}
+
+// CanHost with the Pods moved to the node in the cycle counted as well
+func canHost(snapshot *Snapshot, node *corev1.Node, pod *corev1.Pod, moved []*corev1.Pod) bool {
+	if _, schedulable := snapshot.PodsPerNode[node.Name]; !schedulable {
+		return false
+	}
+	for key, value := range pod.Spec.NodeSelector {
+		if node.Labels[key] != value {
+			return false
+		}
+	}
+	onNode := len(snapshot.AllPodsPerNode[node.Name]) + len(moved)
+	if capacity := node.Status.Allocatable.Pods().Value(); capacity > 0 && int64(onNode) >= capacity {
+		return false
+	}
+	cpu, memory, known := requestedFractions(&ScoreContext{Snapshot: snapshot, Pod: pod}, node)
+	if !known {
+		return true
+	}
+	for _, m := range moved {
+		movedCPU, movedMemory := podRequests(m)
+		cpu += float64(movedCPU) / float64(node.Status.Allocatable.Cpu().MilliValue())
+		memory += float64(movedMemory) / float64(node.Status.Allocatable.Memory().Value())
+	}
+	return cpu <= 1 && memory <= 1
+}
+
+// The planner view of the node: the Pods of the snapshot and the ones moved to it by the earlier moves of the cycle
+func (pl *planner) canHost(node *corev1.Node, pod *corev1.Pod) bool {
+	return canHost(pl.snapshot, node, pod, pl.reserved[node.Name])
+}
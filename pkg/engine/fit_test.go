@@ -0,0 +1,85 @@
+package engine
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const gi = 1024 * 1024 * 1024
+
+func TestCanHost(t *testing.T) {
+	selecting := func(pod corev1.Pod, key, value string) corev1.Pod {
+		pod.Spec.NodeSelector = map[string]string{key: value}
+		return pod
+	}
+	labelled := testNode("n1", 0)
+	labelled.Labels["disk"] = "ssd"
+	succeeded := inNamespace(testPod("job", 0, "n1"), "batch")
+	succeeded.Status.Phase = corev1.PodSucceeded
+	cases := []struct {
+		name  string
+		node  corev1.Node
+		pods  []corev1.Pod
+		pod   corev1.Pod
+		moved []corev1.Pod
+		fits  bool
+	}{
+		{name: "empty node", node: testNode("n1", 2), pod: testPod("web", 0, "n0"), fits: true},
+		{name: "cordoned node", node: cordoned(testNode("n1", 0)), pod: testPod("web", 0, "n0")},
+		{
+			name: "pod capacity used by the Pods of the other namespaces",
+			node: testNode("n1", 2),
+			pods: []corev1.Pod{inNamespace(testPod("db", 0, "n1"), "other"), inNamespace(testPod("cache", 0, "n1"), "other")},
+			pod:  testPod("web", 0, "n0"),
+		},
+		{
+			name: "terminated Pods take no room",
+			node: testNode("n1", 1),
+			pods: []corev1.Pod{succeeded},
+			pod:  testPod("web", 0, "n0"),
+			fits: true,
+		},
+		{
+			name:  "pod capacity used by the moves of the cycle",
+			node:  testNode("n1", 2),
+			pods:  []corev1.Pod{testPod("db", 0, "n1")},
+			pod:   testPod("web", 0, "n0"),
+			moved: []corev1.Pod{testPod("cache", 0, "n2")},
+		},
+		{
+			name: "memory requested by the Pods of the other namespaces",
+			node: allocatable(testNode("n1", 0), 4000, 4*gi),
+			pods: []corev1.Pod{requesting(inNamespace(testPod("db", 0, "n1"), "other"), 1000, 3*gi)},
+			pod:  requesting(testPod("web", 0, "n0"), 1000, 2*gi),
+		},
+		{
+			name:  "cpu requested by the moves of the cycle",
+			node:  allocatable(testNode("n1", 0), 2000, 4*gi),
+			pods:  []corev1.Pod{requesting(testPod("db", 0, "n1"), 500, gi)},
+			pod:   requesting(testPod("web", 0, "n0"), 1000, gi),
+			moved: []corev1.Pod{requesting(testPod("cache", 0, "n2"), 1000, gi)},
+		},
+		{
+			name: "requests fit next to every Pod",
+			node: allocatable(testNode("n1", 0), 4000, 4*gi),
+			pods: []corev1.Pod{requesting(inNamespace(testPod("db", 0, "n1"), "other"), 2000, 2*gi)},
+			pod:  requesting(testPod("web", 0, "n0"), 2000, 2*gi),
+			fits: true,
+		},
+		{name: "node selector matches", node: labelled, pod: selecting(testPod("web", 0, "n0"), "disk", "ssd"), fits: true},
+		{name: "node selector does not match", node: labelled, pod: selecting(testPod("web", 0, "n0"), "disk", "hdd")},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			snapshot := observe(t, []corev1.Node{testNode("n0", 0), c.node}, c.pods, testPolicy(t, nil))
+			var moved []*corev1.Pod
+			for i := range c.moved {
+				moved = append(moved, &c.moved[i])
+			}
+			if fits := canHost(snapshot, &snapshot.Nodes[1], &c.pod, moved); fits != c.fits {
+				t.Errorf("expected canHost %v, got %v", c.fits, fits)
+			}
+		})
+	}
+}
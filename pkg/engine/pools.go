@@ -0,0 +1,58 @@
+package engine
+
+import (
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// NodePools returns the pool of every node, the value of its pool label. The nodes without the label are in the "" pool.
+// It returns nil when the pool label is not set, all the nodes are in a single pool then
+func NodePools(nodes []corev1.Node, label string) map[string]string {
+	if len(label) == 0 {
+		return nil
+	}
+	pools := make(map[string]string)
+	for _, node := range nodes {
+		pools[node.Name] = node.Labels[label]
+	}
+	return pools
+}
+
+// The Pods of the group by the pool of their node and the pools ordered by name
+func podsByPool(pods []corev1.Pod, pools map[string]string) (map[string][]corev1.Pod, []string) {
+	byPool := make(map[string][]corev1.Pod)
+	for _, pod := range pods {
+		pool := pools[pod.Spec.NodeName]
+		byPool[pool] = append(byPool[pool], pod)
+	}
+	names := make([]string, 0, len(byPool))
+	for pool := range byPool {
+		names = append(names, pool)
+	}
+	sort.Strings(names)
+	return byPool, names
+}
+
+// The nodes which must not be targets of the Pods of the pool: the ones which must not be targets at all and the ones of the other pools
+func (pl *planner) notTargetsOf(pool string) map[string]bool {
+	if pl.pools == nil {
+		return pl.notTargets
+	}
+	notTargets := outsidePool(pl.pools, pool)
+	for node := range pl.notTargets {
+		notTargets[node] = true
+	}
+	return notTargets
+}
+
+// The nodes which are not in the pool
+func outsidePool(pools map[string]string, pool string) map[string]bool {
+	outside := make(map[string]bool)
+	for node, nodePool := range pools {
+		if nodePool != pool {
+			outside[node] = true
+		}
+	}
+	return outside
+}
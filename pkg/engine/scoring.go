@@ -0,0 +1,263 @@
+package engine
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hortonworks/pod-rescheduler/policy"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Node labels of the zone, the beta one is used by the older clusters
+const (
+	zoneLabel     = "topology.kubernetes.io/zone"
+	zoneLabelBeta = "failure-domain.beta.kubernetes.io/zone"
+)
+
+// Pod capacity of a node which does not report it, the kubelet default
+const defaultMaxPods = 110
+
+// MissPenalty is subtracted from the score of a node for every recent replacement of the group which missed it
+const MissPenalty = 25
+
+// ScorePlugin rates a candidate target node of a Pod from 0 to 100, the higher the better
+type ScorePlugin interface {
+	Score(c *ScoreContext, node *corev1.Node) int
+}
+
+// ScoreFunc is a ScorePlugin implemented by a function
+type ScoreFunc func(c *ScoreContext, node *corev1.Node) int
+
+// Score calls the function
+func (f ScoreFunc) Score(c *ScoreContext, node *corev1.Node) int {
+	return f(c, node)
+}
+
+// ScoreContext is the move the candidate nodes are scored for
+type ScoreContext struct {
+	Snapshot   *Snapshot
+	Group      string
+	Pod        *corev1.Pod
+	Candidates []*corev1.Node
+	// Recent misses of the group by target node
+	Misses map[string]int
+	// The candidates created within the maximum age are preferred when it is set
+	NewNodeMaxAge time.Duration
+	Now           time.Time
+
+	// Pods of the group per zone, computed once for every candidate
+	groupPerZone map[string]int
+}
+
+// The score plugins by the names used in the policy
+var scorePlugins = map[string]ScorePlugin{
+	policy.ScoreLeastRequested:     ScoreFunc(leastRequested),
+	policy.ScoreBalancedAllocation: ScoreFunc(balancedAllocation),
+	policy.ScoreFewestPods:         ScoreFunc(fewestPods),
+	policy.ScoreImageLocality:      ScoreFunc(imageLocality),
+	policy.ScoreNodeAge:            ScoreFunc(nodeAge),
+	policy.ScoreTopologySpread:     ScoreFunc(topologySpread),
+}
+
+// ScoredNode is a candidate target node and its score
+type ScoredNode struct {
+	Node  *corev1.Node
+	Score int
+}
+
+// BestNode returns the candidate with the highest score and its score: the weighted score of the plugins minus
+// MissPenalty for every recent target miss of the node, the ties are broken by the node name.
+// When the new nodes are preferred and some candidates are new, only the new ones are scored
+func BestNode(c *ScoreContext, scoring *policy.Scoring) (*corev1.Node, int) {
+	ranked := RankNodes(c, scoring)
+	if len(ranked) == 0 {
+		return nil, -1
+	}
+	return ranked[0].Node, ranked[0].Score
+}
+
+// RankNodes orders the candidates the way BestNode chooses among them, from the best to the worst
+func RankNodes(c *ScoreContext, scoring *policy.Scoring) []ScoredNode {
+	candidates := newNodes(c.Candidates, c.NewNodeMaxAge, c.Now)
+	ranked := make([]ScoredNode, 0, len(candidates))
+	for _, node := range candidates {
+		ranked = append(ranked, ScoredNode{Node: node, Score: clamp(NodeScore(c, node, scoring) - c.Misses[node.Name]*MissPenalty)})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return ranked[i].Node.Name < ranked[j].Node.Name
+	})
+	return ranked
+}
+
+// NodeScore is the weighted average of the scores of the enabled plugins, 0 if every weight is 0
+func NodeScore(c *ScoreContext, node *corev1.Node, scoring *policy.Scoring) int {
+	total, weights := 0, 0
+	for _, plugin := range scoring.EnabledPlugins() {
+		if plugin.Weight == 0 {
+			continue
+		}
+		total += plugin.Weight * clamp(scorePlugins[plugin.Name].Score(c, node))
+		weights += plugin.Weight
+	}
+	if weights == 0 {
+		return 0
+	}
+	return total / weights
+}
+
+func clamp(score int) int {
+	switch {
+	case score < 0:
+		return 0
+	case score > 100:
+		return 100
+	}
+	return score
+}
+
+// The fractions of the allocatable CPU and memory of the node requested by its Pods and the moved Pod.
+// The Pods of every namespace are counted, not only the ones the rescheduler may move
+func requestedFractions(c *ScoreContext, node *corev1.Node) (float64, float64, bool) {
+	allocatableCPU := node.Status.Allocatable.Cpu().MilliValue()
+	allocatableMemory := node.Status.Allocatable.Memory().Value()
+	if allocatableCPU == 0 || allocatableMemory == 0 {
+		return 0, 0, false
+	}
+	cpu, memory := podRequests(c.Pod)
+	for i := range c.Snapshot.AllPodsPerNode[node.Name] {
+		podCPU, podMemory := podRequests(&c.Snapshot.AllPodsPerNode[node.Name][i])
+		cpu += podCPU
+		memory += podMemory
+	}
+	return float64(cpu) / float64(allocatableCPU), float64(memory) / float64(allocatableMemory), true
+}
+
+// The CPU in millicores and the memory in bytes requested by the containers of the Pod
+func podRequests(pod *corev1.Pod) (int64, int64) {
+	var cpu, memory int64
+	for _, container := range pod.Spec.Containers {
+		cpu += container.Resources.Requests.Cpu().MilliValue()
+		memory += container.Resources.Requests.Memory().Value()
+	}
+	return cpu, memory
+}
+
+func leastRequested(c *ScoreContext, node *corev1.Node) int {
+	cpu, memory, known := requestedFractions(c, node)
+	if !known {
+		return 0
+	}
+	return int(((1-cpu)*100 + (1-memory)*100) / 2)
+}
+
+func balancedAllocation(c *ScoreContext, node *corev1.Node) int {
+	cpu, memory, known := requestedFractions(c, node)
+	if !known || cpu > 1 || memory > 1 {
+		return 0
+	}
+	difference := cpu - memory
+	if difference < 0 {
+		difference = -difference
+	}
+	return int((1 - difference) * 100)
+}
+
+func fewestPods(c *ScoreContext, node *corev1.Node) int {
+	capacity := node.Status.Allocatable.Pods().Value()
+	if capacity == 0 {
+		capacity = defaultMaxPods
+	}
+	return int(100 - int64(len(c.Snapshot.AllPodsPerNode[node.Name]))*100/capacity)
+}
+
+func imageLocality(c *ScoreContext, node *corev1.Node) int {
+	if len(c.Pod.Spec.Containers) == 0 {
+		return 0
+	}
+	images := make(map[string]bool)
+	for _, image := range node.Status.Images {
+		for _, name := range image.Names {
+			images[name] = true
+		}
+	}
+	present := 0
+	for _, container := range c.Pod.Spec.Containers {
+		if images[container.Image] || images[normalizedImage(container.Image)] {
+			present++
+		}
+	}
+	return present * 100 / len(c.Pod.Spec.Containers)
+}
+
+// The image name as the nodes report it, with the default registry and tag
+func normalizedImage(image string) string {
+	if !strings.Contains(image[strings.LastIndex(image, "/")+1:], ":") && !strings.Contains(image, "@") {
+		image += ":latest"
+	}
+	if !strings.Contains(image, "/") {
+		image = "library/" + image
+	}
+	if first := image[:strings.Index(image, "/")]; !strings.ContainsAny(first, ".:") && first != "localhost" {
+		image = "docker.io/" + image
+	}
+	return image
+}
+
+// The newest candidate scores 100, the oldest one 0
+func nodeAge(c *ScoreContext, node *corev1.Node) int {
+	oldest, newest := node.CreationTimestamp.Time, node.CreationTimestamp.Time
+	for _, candidate := range c.Candidates {
+		created := candidate.CreationTimestamp.Time
+		if created.Before(oldest) {
+			oldest = created
+		}
+		if created.After(newest) {
+			newest = created
+		}
+	}
+	if !newest.After(oldest) {
+		return 100
+	}
+	return int(float64(node.CreationTimestamp.Time.Sub(oldest)) / float64(newest.Sub(oldest)) * 100)
+}
+
+// The zone of the candidate scores 100 when it runs the fewest Pods of the group among the zones of the candidates
+// and 0 when it runs the most. Nodes without a zone are a zone of their own
+func topologySpread(c *ScoreContext, node *corev1.Node) int {
+	if c.groupPerZone == nil {
+		c.groupPerZone = make(map[string]int)
+		for i := range c.Snapshot.Nodes {
+			name := c.Snapshot.Nodes[i].Name
+			c.groupPerZone[zoneOf(&c.Snapshot.Nodes[i])] += countGroup(c.Snapshot.PodsPerNode[name], c.Group) +
+				countGroup(c.Snapshot.PodsOnExcludedNodes[name], c.Group)
+		}
+	}
+	least, most := -1, 0
+	for _, candidate := range c.Candidates {
+		count := c.groupPerZone[zoneOf(candidate)]
+		if least < 0 || count < least {
+			least = count
+		}
+		if count > most {
+			most = count
+		}
+	}
+	if most == least {
+		return 100
+	}
+	return (most - c.groupPerZone[zoneOf(node)]) * 100 / (most - least)
+}
+
+func zoneOf(node *corev1.Node) string {
+	if zone, found := node.Labels[zoneLabel]; found {
+		return zone
+	}
+	if zone, found := node.Labels[zoneLabelBeta]; found {
+		return zone
+	}
+	return "node:" + node.Name
+}
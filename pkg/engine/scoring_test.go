@@ -0,0 +1,38 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/hortonworks/pod-rescheduler/policy"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestScorePluginsCountEveryPod(t *testing.T) {
+	nodes := []corev1.Node{allocatable(testNode("n0", 10), 4000, 4*gi), allocatable(testNode("n1", 10), 4000, 4*gi)}
+	pods := []corev1.Pod{
+		requesting(inNamespace(testPod("db", 0, "n0"), "other"), 2000, 2*gi),
+		requesting(inNamespace(testPod("db", 1, "n0"), "other"), 1000, gi),
+	}
+	snapshot := observe(t, nodes, pods, testPolicy(t, nil))
+	pod := requesting(testPod("web", 0, ""), 1000, gi)
+	c := &ScoreContext{Snapshot: snapshot, Group: "default/web", Pod: &pod, Candidates: []*corev1.Node{&snapshot.Nodes[0], &snapshot.Nodes[1]}}
+
+	cases := []struct {
+		plugin string
+		scores [2]int
+	}{
+		{plugin: policy.ScoreLeastRequested, scores: [2]int{0, 75}},
+		{plugin: policy.ScoreBalancedAllocation, scores: [2]int{100, 100}},
+		{plugin: policy.ScoreFewestPods, scores: [2]int{80, 100}},
+	}
+	for _, tc := range cases {
+		for i := range snapshot.Nodes {
+			if score := scorePlugins[tc.plugin].Score(c, &snapshot.Nodes[i]); score != tc.scores[i] {
+				t.Errorf("%s score of %s: expected %d, got %d", tc.plugin, snapshot.Nodes[i].Name, tc.scores[i], score)
+			}
+		}
+	}
+	if best, _ := BestNode(c, &policy.Scoring{}); best == nil || best.Name != "n1" {
+		t.Errorf("expected the node without Pods to be the best, got: %v", best)
+	}
+}
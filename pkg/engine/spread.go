@@ -0,0 +1,350 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/hortonworks/pod-rescheduler/policy"
+	utils "github.com/hortonworks/pod-rescheduler/utils"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Annotations of the Pods overriding the spread limits of the policy for their group
+const (
+	MaxSkewAnnotation        = "pod-rescheduler.hortonworks.com/max-skew"
+	MaxPodsPerNodeAnnotation = "pod-rescheduler.hortonworks.com/max-pods-per-node"
+)
+
+// spreadProposal is the evaluation of a group by the spread strategy: the skipped Pods
+// and the Pod to move with its candidate target nodes from the best to the worst
+type spreadProposal struct {
+	actions []Action
+	move    *Action
+	targets []ScoredNode
+}
+
+// Move one Pod of every group whose spread exceeds the limits, from the most loaded node to one of the least loaded ones.
+// The groups are evaluated in parallel, then their moves are applied one by one in the order of the group names:
+// a move goes to the best candidate which still has room after the earlier moves of the cycle
+func (pl *planner) spread() {
+	var groups []string
+	for group, pods := range pl.groups {
+		if pl.policy.Strategies.Spread.Namespaces.Contains(pods[0].Namespace) {
+			groups = append(groups, group)
+		}
+	}
+	sort.Strings(groups)
+	proposals := make([]spreadProposal, len(groups))
+	pl.parallel(len(groups), func(i int) {
+		proposals[i] = pl.evaluateSpread(groups[i])
+	})
+	for i := range proposals {
+		pl.applySpread(&proposals[i])
+	}
+}
+
+// The evaluation of the group without changing the planner, so the groups can be evaluated in parallel.
+// The group is reported balanced only when its spread is within the limits, otherwise the skips tell why no Pod is moved
+func (pl *planner) evaluateSpread(group string) spreadProposal {
+	p := pl.policy
+	pods := pl.groups[group]
+	if blocked := pl.groupBlocked(SpreadStrategy, group); blocked != nil {
+		return spreadProposal{actions: []Action{*blocked}}
+	}
+	spread, notTargets := pl.groupSpread(pods)
+	pod, reason, skips := FindMovablePod(group, pods, spread, p, pl.state.Now)
+	if pod == nil {
+		if !spread.Exceeded() {
+			skips = append(skips, Action{Type: ActionSkip, Strategy: SpreadStrategy, Group: group, Reason: ReasonBalanced})
+		}
+		return spreadProposal{actions: skips}
+	}
+	return spreadProposal{
+		actions: skips,
+		move: &Action{
+			Strategy: SpreadStrategy,
+			Group:    group,
+			Pod:      pod,
+			Reason:   reason,
+			Inputs: map[string]string{
+				"groupPods":       strconv.Itoa(len(pods)),
+				"minReplicaCount": strconv.Itoa(p.Strategies.Spread.MinReplicaCount),
+				"skew":            strconv.Itoa(spread.Skew()),
+				"maxSkew":         strconv.Itoa(spread.MaxSkew),
+				"maxPodsPerNode":  strconv.Itoa(spread.MaxPodsPerNode),
+			},
+		},
+		targets: FindNodesForPod(pl.scoreContext(group, pod), spread, notTargets, &p.Scoring),
+	}
+}
+
+// Add the actions of the evaluated group, the move is subject to the eviction limit and the room left on the targets
+func (pl *planner) applySpread(e *spreadProposal) {
+	pl.actions = append(pl.actions, e.actions...)
+	move := e.move
+	if move == nil || pl.rateLimited(SpreadStrategy, move.Group, move.Pod) {
+		return
+	}
+	for _, target := range e.targets {
+		if pl.canHost(target.Node, move.Pod) {
+			move.Target = target.Node.Name
+			move.Inputs["targetScore"] = strconv.Itoa(target.Score)
+			pl.move(*move)
+			return
+		}
+	}
+	move.Inputs["candidateNodes"] = strconv.Itoa(pl.candidateNodes())
+	pl.skip(SpreadStrategy, move.Group, move.Pod, ReasonNoTargetNode, move.Inputs)
+}
+
+// The spread of the group in the first pool where it exceeds its limits, or in the first pool if it does not exceed them in any of them.
+// The nodes which must not be targets of the Pods of the pool are returned as well
+func (pl *planner) groupSpread(pods []corev1.Pod) (*GroupSpread, map[string]bool) {
+	strategy := &pl.policy.Strategies.Spread
+	if pl.pools == nil {
+		return NewGroupSpread(pl.snapshot, pods, pl.notTargets, strategy), pl.notTargets
+	}
+	byPool, pools := podsByPool(pods, pl.pools)
+	var first *GroupSpread
+	var firstNotTargets map[string]bool
+	for _, pool := range pools {
+		notTargets := pl.notTargetsOf(pool)
+		spread := NewGroupSpread(pl.snapshot, byPool[pool], notTargets, strategy)
+		if spread.Exceeded() {
+			return spread, notTargets
+		}
+		if first == nil {
+			first, firstNotTargets = spread, notTargets
+		}
+	}
+	return first, firstNotTargets
+}
+
+// GroupSpread is the number of Running and Ready Pods of a group on the schedulable nodes which run them or can run them,
+// and the limits of the group. The Pods on the unschedulable or tainted nodes are not part of the spread, they are never moved by it
+type GroupSpread struct {
+	Counts         map[string]int
+	MaxSkew        int
+	MaxPodsPerNode int
+}
+
+// NewGroupSpread counts the Pods of the group. The excluded nodes are counted only when they run Pods of the group.
+// The limits of the strategy are overridden by the annotations of the first Pod of the group having them
+func NewGroupSpread(snapshot *Snapshot, pods []corev1.Pod, excluded map[string]bool, strategy *policy.SpreadStrategy) *GroupSpread {
+	s := &GroupSpread{
+		Counts:         groupCounts(snapshot, pods, excluded),
+		MaxSkew:        strategy.MaxSkew,
+		MaxPodsPerNode: strategy.MaxPodsPerNode,
+	}
+	for _, pod := range sortedByName(pods) {
+		if value, err := strconv.Atoi(pod.Annotations[MaxSkewAnnotation]); err == nil && value >= 1 {
+			s.MaxSkew = value
+			break
+		}
+	}
+	for _, pod := range sortedByName(pods) {
+		if value, err := strconv.Atoi(pod.Annotations[MaxPodsPerNodeAnnotation]); err == nil && value >= 0 {
+			s.MaxPodsPerNode = value
+			break
+		}
+	}
+	return s
+}
+
+func groupCounts(snapshot *Snapshot, pods []corev1.Pod, excluded map[string]bool) map[string]int {
+	counts := make(map[string]int)
+	for i := range snapshot.Nodes {
+		if node := &snapshot.Nodes[i]; !excluded[node.Name] && CanHost(snapshot, node, &pods[0]) {
+			counts[node.Name] = 0
+		}
+	}
+	for i := range pods {
+		if _, schedulable := snapshot.PodsPerNode[pods[i].Spec.NodeName]; !schedulable {
+			continue
+		}
+		if pods[i].Status.Phase == corev1.PodRunning && IsPodReady(&pods[i]) {
+			counts[pods[i].Spec.NodeName]++
+		}
+	}
+	return counts
+}
+
+// MostLoaded returns the node running the most Pods of the group, the first one by name on a tie
+func (s *GroupSpread) MostLoaded() (string, int) {
+	most, count := "", -1
+	for node, c := range s.Counts {
+		if c > count || (c == count && node < most) {
+			most, count = node, c
+		}
+	}
+	return most, count
+}
+
+// LeastLoaded returns the fewest Pods of the group on a node
+func (s *GroupSpread) LeastLoaded() int {
+	least := -1
+	for _, c := range s.Counts {
+		if least < 0 || c < least {
+			least = c
+		}
+	}
+	return least
+}
+
+// Exceeded tells whether the most loaded node runs more Pods of the group than the limits allow
+func (s *GroupSpread) Exceeded() bool {
+	_, most := s.MostLoaded()
+	return (s.MaxPodsPerNode > 0 && most > s.MaxPodsPerNode) || most-s.LeastLoaded() > s.MaxSkew
+}
+
+// Skew is the difference between the Pods of the group on the most and the least loaded node
+func (s *GroupSpread) Skew() int {
+	if len(s.Counts) == 0 {
+		return 0
+	}
+	_, most := s.MostLoaded()
+	return most - s.LeastLoaded()
+}
+
+// FindMovablePod finds a Pod on the most loaded node of the group when the spread exceeds its limits, the reason of the move is returned as well.
+// Pods can be moved only when the minimum replica count is met and the policy allows to move them,
+// the Pods which are not considered are returned as skip actions. Every Running and Ready Pod of the group counts in the minimum replica count,
+// the ones on the excluded nodes as well, but only the Pods on the most loaded node of the spread are moved
+func FindMovablePod(group string, pods []corev1.Pod, spread *GroupSpread, p *policy.Config, now time.Time) (*corev1.Pod, string, []Action) {
+	var skips []Action
+	podCount := 0
+	for i, pod := range pods {
+		if pod.Status.Phase != corev1.PodRunning || len(pod.Status.ContainerStatuses) == 0 || !IsPodReady(&pod) {
+			skips = append(skips, Action{Type: ActionSkip, Strategy: SpreadStrategy, Group: group, Pod: &pods[i], Reason: ReasonNotReady})
+			continue
+		}
+		podCount++
+	}
+	source, most := spread.MostLoaded()
+	least := spread.LeastLoaded()
+	var reason string
+	switch {
+	case spread.MaxPodsPerNode > 0 && most > spread.MaxPodsPerNode:
+		reason = fmt.Sprintf("node %s runs %d running and ready pods of the group, more than the maximum %d per node",
+			source, most, spread.MaxPodsPerNode)
+	case most-least > spread.MaxSkew:
+		reason = fmt.Sprintf("node %s runs %d running and ready pods of the group and the least loaded node runs %d, more than the maximum skew %d",
+			source, most, least, spread.MaxSkew)
+	default:
+		return nil, "", skips
+	}
+
+	var podCandidate *corev1.Pod
+	for _, pod := range sortedByName(pods) {
+		if pod.Spec.NodeName != source || pod.Status.Phase != corev1.PodRunning || !IsPodReady(pod) {
+			continue
+		}
+		if notEligible := podNotEligible(pod, p, now); len(notEligible) > 0 {
+			skips = append(skips, Action{Type: ActionSkip, Strategy: SpreadStrategy, Group: group, Pod: pod, Reason: notEligible})
+			continue
+		}
+		podCandidate = pod
+		break
+	}
+	if podCandidate == nil || podCount >= p.Strategies.Spread.MinReplicaCount {
+		return podCandidate, reason, skips
+	}
+	skips = append(skips, Action{Type: ActionSkip, Strategy: SpreadStrategy, Group: group, Pod: podCandidate, Reason: ReasonMinReplica, Inputs: map[string]string{
+		"readyPods":       strconv.Itoa(podCount),
+		"minReplicaCount": strconv.Itoa(p.Strategies.Spread.MinReplicaCount),
+	}})
+	return nil, "", skips
+}
+
+// The Pods ordered by name, pointing to the elements of the slice
+func sortedByName(pods []corev1.Pod) []*corev1.Pod {
+	sorted := make([]*corev1.Pod, len(pods))
+	for i := range pods {
+		sorted[i] = &pods[i]
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+	return sorted
+}
+
+// The reason why the policy does not allow to move the Pod, empty if it is eligible
+func podNotEligible(pod *corev1.Pod, p *policy.Config, now time.Time) string {
+	if !p.Eligibility.Matches(pod.Labels) {
+		return ReasonNotSelected
+	}
+	if !p.Eligibility.OldEnough(pod.CreationTimestamp.Time, now) {
+		return ReasonTooYoung
+	}
+	if notSafeToEvict(pod) {
+		return ReasonNotSafeToEvict
+	}
+	return ""
+}
+
+// IsPodReady tells whether every container of the Pod is ready
+func IsPodReady(pod *corev1.Pod) bool {
+	for _, cStatus := range pod.Status.ContainerStatuses {
+		if !cStatus.Ready {
+			return false
+		}
+	}
+	return true
+}
+
+// FindNodesForPod ranks the least loaded nodes of the group which can run the Pod by their score, from the best to the worst.
+// Only the nodes whose load stays below the source node after the move are considered, so that the move reduces the skew,
+// and the ones below the maximum Pods per node. The excluded nodes are not considered. The candidates are set in the score context
+func FindNodesForPod(c *ScoreContext, spread *GroupSpread, excluded map[string]bool, scoring *policy.Scoring) []ScoredNode {
+	snapshot := c.Snapshot
+	source := spread.Counts[c.Pod.Spec.NodeName]
+	var candidates []*corev1.Node
+	least := -1
+	for i, node := range snapshot.Nodes {
+		count, eligible := spread.Counts[node.Name]
+		if !eligible || excluded[node.Name] || count > source-2 || (spread.MaxPodsPerNode > 0 && count >= spread.MaxPodsPerNode) {
+			continue
+		}
+		if least < 0 || count < least {
+			candidates, least = nil, count
+		}
+		if count == least {
+			candidates = append(candidates, &snapshot.Nodes[i])
+		}
+	}
+	c.Candidates = candidates
+	return RankNodes(c, scoring)
+}
+
+func countGroup(pods []corev1.Pod, group string) int {
+	count := 0
+	for _, pod := range pods {
+		if groupName := utils.GetPodGroupName(&pod); groupName != nil && *groupName == group {
+			count++
+		}
+	}
+	return count
+}
+
+// GroupSkews returns the difference between the most and the least Pods of every group on the nodes which run them or can run them,
+// by the pool of the nodes. The pool is "" when the nodes are not partitioned into pools
+func GroupSkews(snapshot *Snapshot, poolLabel string) map[string]map[string]int {
+	skews := make(map[string]map[string]int)
+	pools := NodePools(snapshot.Nodes, poolLabel)
+	for group, pods := range snapshot.Groups() {
+		skews[group] = make(map[string]int)
+		if pools == nil {
+			spread := GroupSpread{Counts: groupCounts(snapshot, pods, nil)}
+			skews[group][""] = spread.Skew()
+			continue
+		}
+		byPool, _ := podsByPool(pods, pools)
+		for pool, poolPods := range byPool {
+			spread := GroupSpread{Counts: groupCounts(snapshot, poolPods, outsidePool(pools, pool))}
+			skews[group][pool] = spread.Skew()
+		}
+	}
+	return skews
+}
@@ -0,0 +1,191 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hortonworks/pod-rescheduler/policy"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestFindMovablePodCountsPodsOnExcludedNodes(t *testing.T) {
+	p := testPolicy(t, func(p *policy.Config) { p.Strategies.Spread.MinReplicaCount = 3 })
+	nodes := []corev1.Node{testNode("n0", 0), testNode("n1", 0), cordoned(testNode("n2", 0))}
+	pods := []corev1.Pod{testPod("web", 0, "n0"), testPod("web", 1, "n0"), testPod("web", 2, "n2")}
+	snapshot := observe(t, nodes, pods, p)
+
+	group := snapshot.Groups()["default/web"]
+	spread := NewGroupSpread(snapshot, group, nil, &p.Strategies.Spread)
+	if _, excluded := spread.Counts["n2"]; excluded {
+		t.Errorf("the excluded node is part of the spread: %v", spread.Counts)
+	}
+	pod, _, skips := FindMovablePod("default/web", group, spread, p, testNow)
+	if pod == nil || pod.Name != "web-0" || len(skips) > 0 {
+		t.Fatalf("expected web-0 to be moved, got: %v, skips: %v", pod, describe(skips))
+	}
+	equalActions(t, Plan(snapshot, testState(), p), []string{"move spread default/web default/web-0 -> n1"})
+}
+
+func TestPlanReportsBalancedOnlyWithinTheLimits(t *testing.T) {
+	nodes := []corev1.Node{testNode("n0", 0), testNode("n1", 0)}
+	cases := []struct {
+		name   string
+		pods   []corev1.Pod
+		policy func(p *policy.Config)
+		want   []string
+	}{
+		{
+			name: "skew within the limit",
+			pods: []corev1.Pod{testPod("web", 0, "n0"), testPod("web", 1, "n1")},
+			want: []string{"skip spread default/web balanced"},
+		},
+		{
+			name:   "skew blocked by the minimum replica count",
+			pods:   []corev1.Pod{testPod("web", 0, "n0"), testPod("web", 1, "n0"), notReady(testPod("web", 2, "n0"))},
+			policy: func(p *policy.Config) { p.Strategies.Spread.MinReplicaCount = 3 },
+			want: []string{
+				"skip spread default/web default/web-2 not_ready",
+				"skip spread default/web default/web-0 min_replica_count",
+			},
+		},
+		{
+			name: "skew blocked by the pod selector",
+			pods: []corev1.Pod{testPod("web", 0, "n0"), testPod("web", 1, "n0")},
+			policy: func(p *policy.Config) {
+				p.Strategies.Spread.MinReplicaCount = 1
+				p.Eligibility.PodSelector = "movable=true"
+			},
+			want: []string{
+				"skip spread default/web default/web-0 pod_selector",
+				"skip spread default/web default/web-1 pod_selector",
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p := testPolicy(t, c.policy)
+			equalActions(t, Plan(observe(t, nodes, c.pods, p), testState(), p), c.want)
+		})
+	}
+}
+
+func TestFindMovablePod(t *testing.T) {
+	young := testPod("web", 0, "n0")
+	young.CreationTimestamp = metav1.NewTime(testNow)
+	limited := testPod("web", 0, "n0")
+	limited.Annotations = map[string]string{MaxPodsPerNodeAnnotation: "1"}
+	cases := []struct {
+		name   string
+		pods   []corev1.Pod
+		policy func(p *policy.Config)
+		pod    string
+		skips  []string
+	}{
+		{
+			name: "the spread is within the limits",
+			pods: []corev1.Pod{testPod("web", 0, "n0"), testPod("web", 1, "n1")},
+		},
+		{
+			name: "the first Pod of the most loaded node by name",
+			pods: []corev1.Pod{testPod("web", 1, "n0"), testPod("web", 0, "n0")},
+			pod:  "web-0",
+		},
+		{
+			name:   "the Pods which are not eligible are skipped",
+			pods:   []corev1.Pod{young, testPod("web", 1, "n0")},
+			policy: func(p *policy.Config) { p.Eligibility.MinPodAge = metav1.Duration{Duration: time.Minute} },
+			pod:    "web-1",
+			skips:  []string{"skip spread default/web default/web-0 min_pod_age"},
+		},
+		{
+			name:   "the Pods which are not ready do not count in the minimum replica count",
+			pods:   []corev1.Pod{testPod("web", 0, "n0"), testPod("web", 1, "n0"), notReady(testPod("web", 2, "n1"))},
+			policy: func(p *policy.Config) { p.Strategies.Spread.MinReplicaCount = 3 },
+			skips: []string{
+				"skip spread default/web default/web-2 not_ready",
+				"skip spread default/web default/web-0 min_replica_count",
+			},
+		},
+		{
+			name: "the maximum Pods per node of the annotation",
+			pods: []corev1.Pod{limited, testPod("web", 1, "n0"), testPod("web", 2, "n1")},
+			pod:  "web-0",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p := testPolicy(t, c.policy)
+			snapshot := observe(t, []corev1.Node{testNode("n0", 0), testNode("n1", 0)}, c.pods, p)
+			group := snapshot.Groups()["default/web"]
+			spread := NewGroupSpread(snapshot, group, nil, &p.Strategies.Spread)
+			pod, reason, skips := FindMovablePod("default/web", group, spread, p, testNow)
+			var name string
+			if pod != nil {
+				name = pod.Name
+			}
+			if name != c.pod || (pod != nil && len(reason) == 0) {
+				t.Errorf("expected the movable Pod %q with a reason, got: %q, reason: %q", c.pod, name, reason)
+			}
+			equalActions(t, skips, c.skips)
+		})
+	}
+}
+
+func TestFindNodesForPod(t *testing.T) {
+	cases := []struct {
+		name     string
+		pods     []corev1.Pod
+		excluded map[string]bool
+		policy   func(p *policy.Config)
+		want     []string
+	}{
+		{
+			name: "the least loaded nodes",
+			pods: []corev1.Pod{testPod("web", 0, "n0"), testPod("web", 1, "n0"), testPod("web", 2, "n0"), testPod("web", 3, "n1")},
+			want: []string{"n2"},
+		},
+		{
+			name: "no node where the move reduces the skew",
+			pods: []corev1.Pod{testPod("web", 0, "n0"), testPod("web", 1, "n0"), testPod("web", 2, "n1"), testPod("web", 3, "n2")},
+		},
+		{
+			name:     "the excluded nodes are not considered",
+			pods:     []corev1.Pod{testPod("web", 0, "n0"), testPod("web", 1, "n0")},
+			excluded: map[string]bool{"n1": true},
+			want:     []string{"n2"},
+		},
+		{
+			name: "the nodes at the maximum Pods per node are not considered",
+			pods: []corev1.Pod{
+				testPod("web", 0, "n0"), testPod("web", 1, "n0"), testPod("web", 2, "n0"), testPod("web", 3, "n1"), testPod("web", 4, "n2"),
+			},
+			policy: func(p *policy.Config) { p.Strategies.Spread.MaxPodsPerNode = 1 },
+		},
+		{
+			name: "the candidates are ranked by their score",
+			pods: []corev1.Pod{testPod("web", 0, "n0"), testPod("web", 1, "n0"), inNamespace(testPod("db", 0, "n1"), "other")},
+			policy: func(p *policy.Config) {
+				p.Scoring.Plugins = []policy.WeightedScorePlugin{{Name: policy.ScoreFewestPods, Weight: 1}}
+			},
+			want: []string{"n2", "n1"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p := testPolicy(t, c.policy)
+			nodes := []corev1.Node{testNode("n0", 10), testNode("n1", 10), testNode("n2", 10)}
+			snapshot := observe(t, nodes, c.pods, p)
+			group := snapshot.Groups()["default/web"]
+			spread := NewGroupSpread(snapshot, group, nil, &p.Strategies.Spread)
+			var ranked []string
+			for _, node := range FindNodesForPod(&ScoreContext{Snapshot: snapshot, Group: "default/web", Pod: &group[0]}, spread, c.excluded, &p.Scoring) {
+				ranked = append(ranked, node.Node.Name)
+			}
+			if strings.Join(ranked, ",") != strings.Join(c.want, ",") {
+				t.Errorf("expected the nodes %v, got: %v", c.want, ranked)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/hortonworks/pod-rescheduler/audit"
+	"github.com/hortonworks/pod-rescheduler/metrics"
+	"github.com/hortonworks/pod-rescheduler/pkg/engine"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// Where the replacement of an evicted Pod was placed compared to the target node of the move
+const (
+	placementTarget   = "target"
+	placementOther    = "other"
+	placementSource   = "source"
+	placementNotFound = "not_found"
+)
+
+// targetMisses remembers the target nodes the replacements of a group missed recently.
+// A replacement placed on its target node clears the misses of the node
+type targetMisses struct {
+	expiry time.Duration
+	// group -> target node -> misses
+	misses map[string]map[string]*targetMiss
+	mutex  sync.Mutex
+}
+
+type targetMiss struct {
+	count int
+	last  time.Time
+}
+
+func newTargetMisses(expiry time.Duration) *targetMisses {
+	return &targetMisses{expiry: expiry, misses: make(map[string]map[string]*targetMiss)}
+}
+
+func (t *targetMisses) record(group, target string, hit bool, now time.Time) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if hit {
+		delete(t.misses[group], target)
+		return
+	}
+	if t.misses[group] == nil {
+		t.misses[group] = make(map[string]*targetMiss)
+	}
+	miss, found := t.misses[group][target]
+	if !found {
+		miss = &targetMiss{}
+		t.misses[group][target] = miss
+	}
+	miss.count++
+	miss.last = now
+}
+
+// The recent misses by group and target node, the ones older than the expiry are forgotten
+func (t *targetMisses) current(now time.Time) map[string]map[string]int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	current := make(map[string]map[string]int)
+	for group, nodes := range t.misses {
+		for node, miss := range nodes {
+			if now.Sub(miss.last) >= t.expiry {
+				delete(nodes, node)
+				continue
+			}
+			if current[group] == nil {
+				current[group] = make(map[string]int)
+			}
+			current[group][node] = miss.count
+		}
+		if len(nodes) == 0 {
+			delete(t.misses, group)
+		}
+	}
+	return current
+}
+
+// eviction is an executed move whose replacement is verified
+type eviction struct {
+	action engine.Action
+	at     time.Time
+	// cycle of the eviction, the replacement is verified later
+	cycleID string
+}
+
+// Wait until the replacement of the evicted Pod is Running and Ready, the node of the replacement is verified as soon as it is scheduled
+// and the source node is not steered away from any more. The replacement is the Pod of the same controller created since the eviction
+func (r *rescheduler) waitForReplacement(ctx context.Context, e eviction) (*corev1.Pod, error) {
+	action := e.action
+	waitCtx, cancel := context.WithTimeout(ctx, *podSchedulingTimeout)
+	defer cancel()
+	var replacement *corev1.Pod
+	err := wait.PollUntil(2*time.Second, func() (bool, error) {
+		listCtx, cancelList := r.cycleContext()
+		defer cancelList()
+		pods, err := r.client.ListPods(listCtx, action.Pod.Namespace, "")
+		if err != nil {
+			log.Warningf("Error while looking for the replacement of pod %s: %v", action.Pod.Name, err)
+			return false, nil
+		}
+		found := findReplacement(pods, action.Pod, e.at)
+		if found == nil {
+			return false, nil
+		}
+		if replacement == nil {
+			r.placementVerified(e, found)
+			r.unsteer(action)
+		}
+		replacement = found
+		return found.Status.Phase == corev1.PodRunning && engine.IsPodReady(found), nil
+	}, waitCtx.Done())
+	if replacement == nil {
+		if ctx.Err() == nil {
+			r.placementVerified(e, nil)
+		}
+		r.unsteer(action)
+	}
+	if err != nil {
+		return replacement, fmt.Errorf("the replacement of pod %s is not ready within %v", action.Pod.Name, *podSchedulingTimeout)
+	}
+	return replacement, nil
+}
+
+// The first scheduled Pod of the controller of the evicted Pod created since the eviction.
+// The creation time has a precision of a second, so the time of the eviction is truncated
+func findReplacement(pods []corev1.Pod, evicted *corev1.Pod, evictedAt time.Time) *corev1.Pod {
+	owner := metav1.GetControllerOf(evicted)
+	if owner == nil {
+		return nil
+	}
+	since := evictedAt.Truncate(time.Second)
+	var replacement *corev1.Pod
+	for i := range pods {
+		pod := &pods[i]
+		controller := metav1.GetControllerOf(pod)
+		if controller == nil || controller.UID != owner.UID || pod.UID == evicted.UID || len(pod.Spec.NodeName) == 0 ||
+			pod.CreationTimestamp.Time.Before(since) {
+			continue
+		}
+		if replacement == nil || pod.CreationTimestamp.Time.Before(replacement.CreationTimestamp.Time) ||
+			(pod.CreationTimestamp.Equal(replacement.CreationTimestamp) && pod.Name < replacement.Name) {
+			replacement = pod
+		}
+	}
+	return replacement
+}
+
+// Record where the replacement was placed, a nil replacement was not found. Missing the target penalizes it for the group
+func (r *rescheduler) placementVerified(e eviction, replacement *corev1.Pod) {
+	action := e.action
+	outcome, node := placementNotFound, ""
+	if replacement != nil {
+		node = replacement.Spec.NodeName
+		switch node {
+		case action.Target:
+			outcome = placementTarget
+		case action.Pod.Spec.NodeName:
+			outcome = placementSource
+		default:
+			outcome = placementOther
+		}
+	}
+	r.misses.record(action.Group, action.Target, outcome == placementTarget, r.now())
+	metrics.Placement(action.Strategy, outcome)
+	inputs := map[string]string{"outcome": outcome}
+	entry := decisionLog(action.Strategy, action.Group, action.Pod).WithFields(log.Fields{"target": action.Target, "outcome": outcome})
+	if replacement != nil {
+		inputs["replacement"], inputs["replacementNode"] = replacement.Name, node
+		entry = entry.WithFields(log.Fields{"replacement": replacement.Name, "replacementNode": node})
+	}
+	if outcome == placementTarget {
+		entry.Info("Replacement Pod is placed on the target node")
+	} else {
+		entry.Warn("Replacement Pod missed the target node")
+	}
+	r.audit(audit.Record{
+		CycleID:    e.cycleID,
+		Decision:   audit.Placed,
+		Strategy:   action.Strategy,
+		Group:      action.Group,
+		TargetNode: action.Target,
+		Reason:     action.Reason,
+		Inputs:     inputs,
+		Result:     outcome,
+	}, action.Pod)
+}
@@ -0,0 +1,279 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Config is the rescheduler policy. It can be read from a YAML file given with --policy-config-file,
+// otherwise it is built from the command line flags
+type Config struct {
+	HousekeepingInterval metav1.Duration `json:"housekeepingInterval"`
+	Namespaces           NamespaceScope  `json:"namespaces"`
+	Strategies           Strategies      `json:"strategies"`
+	RateLimits           RateLimits      `json:"rateLimits"`
+	Eligibility          Eligibility     `json:"eligibility"`
+	// MaintenanceWindows restricts when Pods are evicted, the moves are still planned and reported outside of them
+	MaintenanceWindows MaintenanceWindows `json:"maintenanceWindows"`
+	Scoring            Scoring            `json:"scoring"`
+	// NodePoolLabel is the label of the nodes naming their pool, the Pods are moved only between the nodes of the same pool
+	NodePoolLabel string     `json:"nodePoolLabel,omitempty"`
+	Autoscaler    Autoscaler `json:"autoscaler"`
+	// Workers is the number of Pod groups evaluated in parallel, their moves are still applied one by one
+	Workers int `json:"workers"`
+}
+
+// Autoscaler configures the cooperation with the cluster-autoscaler. Its annotations and taints are always honored
+type Autoscaler struct {
+	// PreferNewNodes moves the Pods to the nodes created within NewNodeMaxAge, e.g. the ones the autoscaler just added,
+	// when there is one among the candidate target nodes
+	PreferNewNodes bool            `json:"preferNewNodes"`
+	NewNodeMaxAge  metav1.Duration `json:"newNodeMaxAge"`
+}
+
+// NamespaceScope selects the namespaces the rescheduler acts on.
+// An empty include list means every namespace
+type NamespaceScope struct {
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// Strategies holds the configuration of every rescheduling strategy
+type Strategies struct {
+	Spread         SpreadStrategy         `json:"spread"`
+	NodeConditions NodeConditionsStrategy `json:"nodeConditions"`
+}
+
+// SpreadStrategy moves Pods of the same group from the most loaded node to the least loaded one,
+// when the difference between them is more than the maximum skew or the most loaded node runs more than the maximum Pods per node
+type SpreadStrategy struct {
+	Enabled         bool `json:"enabled"`
+	MinReplicaCount int  `json:"minReplicaCount"`
+	// MaxSkew is the maximum difference between the Pods of a group on the most and the least loaded node which can run them
+	MaxSkew int `json:"maxSkew"`
+	// MaxPodsPerNode is the maximum number of Pods of a group on a node, 0 means no limit.
+	// Both limits can be overridden per group by the annotations of the Pods
+	MaxPodsPerNode int            `json:"maxPodsPerNode,omitempty"`
+	Namespaces     NamespaceScope `json:"namespaces"`
+	// MaintenanceWindows restricts the evictions of the strategy further
+	MaintenanceWindows []MaintenanceWindow `json:"maintenanceWindows,omitempty"`
+}
+
+// NodeConditionsStrategy moves Pods off the nodes which have a bad condition for a while, ordered by their QoS class
+type NodeConditionsStrategy struct {
+	Enabled bool `json:"enabled"`
+	// Conditions lists the node conditions the Pods are evacuated for, the others are ignored
+	Conditions []NodeCondition `json:"conditions"`
+	Namespaces NamespaceScope  `json:"namespaces"`
+	// MaintenanceWindows restricts the evictions of the strategy further
+	MaintenanceWindows []MaintenanceWindow `json:"maintenanceWindows,omitempty"`
+}
+
+// NodeCondition is a node condition type which is bad when its status is True,
+// e.g. MemoryPressure or a node-problem-detector condition like KernelDeadlock
+type NodeCondition struct {
+	Type string `json:"type"`
+	// MinDuration is how long the condition has to be True before the Pods are moved
+	MinDuration metav1.Duration `json:"minDuration"`
+}
+
+// RateLimits bounds how many disruptions the rescheduler may cause
+type RateLimits struct {
+	// MaxEvictionsPerCycle is the maximum number of Pods deleted in one housekeeping cycle, 0 means no limit
+	MaxEvictionsPerCycle int `json:"maxEvictionsPerCycle"`
+	// GroupCooldown is the minimum time between two moves of the same Pod group
+	GroupCooldown metav1.Duration `json:"groupCooldown"`
+}
+
+// Eligibility decides which Pods can be moved at all
+type Eligibility struct {
+	// PodSelector is a label selector, only the matching Pods are moved
+	PodSelector string `json:"podSelector,omitempty"`
+	// ExcludeGroups lists the Pod groups (namespace/name) which are never moved
+	ExcludeGroups []string `json:"excludeGroups,omitempty"`
+	// MinPodAge protects freshly started Pods from being moved again
+	MinPodAge metav1.Duration `json:"minPodAge"`
+
+	selector labels.Selector
+}
+
+// Clone returns a deep copy of the policy. A policy file is parsed into a clone of the defaults,
+// because the decoder reuses the slices and maps of the value it decodes into
+func (c *Config) Clone() Config {
+	clone := *c
+	clone.Namespaces = c.Namespaces.clone()
+	clone.Strategies.Spread.Namespaces = c.Strategies.Spread.Namespaces.clone()
+	clone.Strategies.Spread.MaintenanceWindows = cloneWindows(c.Strategies.Spread.MaintenanceWindows)
+	clone.Strategies.NodeConditions.Conditions = append([]NodeCondition(nil), c.Strategies.NodeConditions.Conditions...)
+	clone.Strategies.NodeConditions.Namespaces = c.Strategies.NodeConditions.Namespaces.clone()
+	clone.Strategies.NodeConditions.MaintenanceWindows = cloneWindows(c.Strategies.NodeConditions.MaintenanceWindows)
+	clone.MaintenanceWindows = c.MaintenanceWindows.clone()
+	clone.Scoring.Plugins = append([]WeightedScorePlugin(nil), c.Scoring.Plugins...)
+	clone.Eligibility.ExcludeGroups = append([]string(nil), c.Eligibility.ExcludeGroups...)
+	return clone
+}
+
+// Validate checks the policy and returns the first problem found
+func (c *Config) Validate() error {
+	if c.HousekeepingInterval.Duration <= 0 {
+		return fmt.Errorf("housekeepingInterval must be positive, got: %v", c.HousekeepingInterval.Duration)
+	}
+	if err := c.Namespaces.validate("namespaces"); err != nil {
+		return err
+	}
+	if c.Workers < 1 {
+		return fmt.Errorf("workers must be at least 1, got: %d", c.Workers)
+	}
+	if len(c.NodePoolLabel) > 0 {
+		if errs := validation.IsQualifiedName(c.NodePoolLabel); len(errs) > 0 {
+			return fmt.Errorf("nodePoolLabel is not a valid label key: %s", strings.Join(errs, ", "))
+		}
+	}
+	if err := c.Strategies.Spread.Namespaces.validate("strategies.spread.namespaces"); err != nil {
+		return err
+	}
+	if err := validateWindows("strategies.spread.maintenanceWindows", c.Strategies.Spread.MaintenanceWindows); err != nil {
+		return err
+	}
+	if c.Strategies.Spread.MinReplicaCount < 1 {
+		return fmt.Errorf("strategies.spread.minReplicaCount must be at least 1, got: %d", c.Strategies.Spread.MinReplicaCount)
+	}
+	if c.Strategies.Spread.MaxSkew < 1 {
+		return fmt.Errorf("strategies.spread.maxSkew must be at least 1, got: %d", c.Strategies.Spread.MaxSkew)
+	}
+	if c.Strategies.Spread.MaxPodsPerNode < 0 {
+		return fmt.Errorf("strategies.spread.maxPodsPerNode must not be negative, got: %d", c.Strategies.Spread.MaxPodsPerNode)
+	}
+	if err := c.Strategies.NodeConditions.validate("strategies.nodeConditions"); err != nil {
+		return err
+	}
+	if err := c.MaintenanceWindows.validate("maintenanceWindows"); err != nil {
+		return err
+	}
+	if err := c.Scoring.validate("scoring"); err != nil {
+		return err
+	}
+	if c.RateLimits.MaxEvictionsPerCycle < 0 {
+		return fmt.Errorf("rateLimits.maxEvictionsPerCycle must not be negative, got: %d", c.RateLimits.MaxEvictionsPerCycle)
+	}
+	if c.RateLimits.GroupCooldown.Duration < 0 {
+		return fmt.Errorf("rateLimits.groupCooldown must not be negative, got: %v", c.RateLimits.GroupCooldown.Duration)
+	}
+	if c.Autoscaler.PreferNewNodes && c.Autoscaler.NewNodeMaxAge.Duration <= 0 {
+		return fmt.Errorf("autoscaler.newNodeMaxAge must be positive when autoscaler.preferNewNodes is set, got: %v", c.Autoscaler.NewNodeMaxAge.Duration)
+	}
+	if c.Eligibility.MinPodAge.Duration < 0 {
+		return fmt.Errorf("eligibility.minPodAge must not be negative, got: %v", c.Eligibility.MinPodAge.Duration)
+	}
+	selector, err := labels.Parse(c.Eligibility.PodSelector)
+	if err != nil {
+		return fmt.Errorf("eligibility.podSelector is invalid: %s", err.Error())
+	}
+	c.Eligibility.selector = selector
+	return nil
+}
+
+func (s *NamespaceScope) validate(field string) error {
+	for _, ns := range s.Include {
+		if len(ns) == 0 {
+			return fmt.Errorf("%s.include must not contain an empty namespace", field)
+		}
+	}
+	for _, ns := range s.Exclude {
+		if len(ns) == 0 {
+			return fmt.Errorf("%s.exclude must not contain an empty namespace", field)
+		}
+	}
+	return nil
+}
+
+func (s *NodeConditionsStrategy) validate(field string) error {
+	if s.Enabled && len(s.Conditions) == 0 {
+		return fmt.Errorf("%s.conditions must not be empty when the strategy is enabled", field)
+	}
+	seen := make(map[string]bool)
+	for i, condition := range s.Conditions {
+		if len(condition.Type) == 0 {
+			return fmt.Errorf("%s.conditions[%d].type must not be empty", field, i)
+		}
+		if seen[condition.Type] {
+			return fmt.Errorf("%s.conditions[%d].type is duplicated: %s", field, i, condition.Type)
+		}
+		seen[condition.Type] = true
+		if condition.MinDuration.Duration < 0 {
+			return fmt.Errorf("%s.conditions[%d].minDuration must not be negative, got: %v", field, i, condition.MinDuration.Duration)
+		}
+	}
+	if err := validateWindows(field+".maintenanceWindows", s.MaintenanceWindows); err != nil {
+		return err
+	}
+	return s.Namespaces.validate(field + ".namespaces")
+}
+
+// Condition returns the configuration of the node condition type, nil if the Pods are not evacuated for it
+func (s *NodeConditionsStrategy) Condition(conditionType string) *NodeCondition {
+	for i := range s.Conditions {
+		if s.Conditions[i].Type == conditionType {
+			return &s.Conditions[i]
+		}
+	}
+	return nil
+}
+
+func (s NamespaceScope) clone() NamespaceScope {
+	return NamespaceScope{Include: append([]string(nil), s.Include...), Exclude: append([]string(nil), s.Exclude...)}
+}
+
+// Contains tells whether the namespace is in the scope
+func (s *NamespaceScope) Contains(namespace string) bool {
+	for _, ns := range s.Exclude {
+		if ns == namespace {
+			return false
+		}
+	}
+	if len(s.Include) == 0 {
+		return true
+	}
+	for _, ns := range s.Include {
+		if ns == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// ListNamespace returns the namespace to list Pods from: the only included one, or all of them
+func (s *NamespaceScope) ListNamespace() string {
+	if len(s.Include) == 1 {
+		return s.Include[0]
+	}
+	return metav1.NamespaceAll
+}
+
+// Excluded tells whether the Pod group is excluded from moves
+func (e *Eligibility) Excluded(group string) bool {
+	for _, g := range e.ExcludeGroups {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches tells whether the Pod labels match the configured selector
+func (e *Eligibility) Matches(podLabels map[string]string) bool {
+	if e.selector == nil {
+		return true
+	}
+	return e.selector.Matches(labels.Set(podLabels))
+}
+
+// OldEnough tells whether the Pod created at the given time is old enough to be moved
+func (e *Eligibility) OldEnough(created time.Time, now time.Time) bool {
+	return now.Sub(created) >= e.MinPodAge.Duration
+}
@@ -0,0 +1,81 @@
+package policy
+
+import "fmt"
+
+// Score plugins rating the candidate target nodes of a Pod
+const (
+	// ScoreLeastRequested prefers the nodes with the most unrequested CPU and memory
+	ScoreLeastRequested = "leastRequested"
+	// ScoreBalancedAllocation prefers the nodes where the requested CPU and memory fractions are the closest
+	ScoreBalancedAllocation = "balancedAllocation"
+	// ScoreFewestPods prefers the nodes running the fewest Pods compared to their Pod capacity
+	ScoreFewestPods = "fewestPods"
+	// ScoreImageLocality prefers the nodes which have the images of the Pod already
+	ScoreImageLocality = "imageLocality"
+	// ScoreNodeAge prefers the newest nodes, e.g. the ones just added by an autoscaler
+	ScoreNodeAge = "nodeAge"
+	// ScoreTopologySpread prefers the zones running the fewest Pods of the group
+	ScoreTopologySpread = "topologySpread"
+)
+
+// ScorePlugins lists every known score plugin
+var ScorePlugins = []string{
+	ScoreLeastRequested, ScoreBalancedAllocation, ScoreFewestPods, ScoreImageLocality, ScoreNodeAge, ScoreTopologySpread,
+}
+
+// Scoring configures how the target node of a move is chosen: every enabled plugin scores the candidate nodes from 0 to 100,
+// the node with the highest weighted score wins and the ties are broken by the node name
+type Scoring struct {
+	// Plugins lists the enabled plugins, DefaultScorePlugins are used when it is empty
+	Plugins []WeightedScorePlugin `json:"plugins,omitempty"`
+}
+
+// WeightedScorePlugin is an enabled score plugin, a weight of 0 disables it
+type WeightedScorePlugin struct {
+	Name   string `json:"name"`
+	Weight int    `json:"weight"`
+}
+
+// DefaultScorePlugins are the plugins used when the policy does not configure any.
+// The node age is left out, the new nodes are not preferred by default
+var DefaultScorePlugins = []WeightedScorePlugin{
+	{Name: ScoreLeastRequested, Weight: 1},
+	{Name: ScoreBalancedAllocation, Weight: 1},
+	{Name: ScoreFewestPods, Weight: 1},
+	{Name: ScoreImageLocality, Weight: 1},
+	{Name: ScoreTopologySpread, Weight: 2},
+}
+
+// EnabledPlugins returns the configured plugins or the default ones
+func (s *Scoring) EnabledPlugins() []WeightedScorePlugin {
+	if len(s.Plugins) == 0 {
+		return DefaultScorePlugins
+	}
+	return s.Plugins
+}
+
+func (s *Scoring) validate(field string) error {
+	seen := make(map[string]bool)
+	for i, plugin := range s.Plugins {
+		if !knownScorePlugin(plugin.Name) {
+			return fmt.Errorf("%s.plugins[%d].name is not a known score plugin: %q, expected one of %v", field, i, plugin.Name, ScorePlugins)
+		}
+		if seen[plugin.Name] {
+			return fmt.Errorf("%s.plugins[%d].name is duplicated: %s", field, i, plugin.Name)
+		}
+		seen[plugin.Name] = true
+		if plugin.Weight < 0 {
+			return fmt.Errorf("%s.plugins[%d].weight must not be negative, got: %d", field, i, plugin.Weight)
+		}
+	}
+	return nil
+}
+
+func knownScorePlugin(name string) bool {
+	for _, known := range ScorePlugins {
+		if known == name {
+			return true
+		}
+	}
+	return false
+}
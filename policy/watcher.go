@@ -0,0 +1,86 @@
+package policy
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"sync"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/ghodss/yaml"
+)
+
+// Load reads the YAML policy file on top of the defaults and validates it
+func Load(path string, defaults Config) (*Config, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file %s: %s", path, err.Error())
+	}
+	return parse(path, content, defaults)
+}
+
+// Parse the content on top of a deep copy of the defaults, so a rejected content changes neither them nor the current policy
+func parse(path string, content []byte, defaults Config) (*Config, error) {
+	config := defaults.Clone()
+	if err := yaml.Unmarshal(content, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse policy file %s: %s", path, err.Error())
+	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid policy file %s: %s", path, err.Error())
+	}
+	return &config, nil
+}
+
+// Watcher keeps the last valid policy read from a file. It polls the file: no change is notified, every Reload reads it again
+// and applies it when the content changed. The content is compared instead of the modification time,
+// so that the symlink swap of a mounted ConfigMap is noticed as well
+type Watcher struct {
+	path     string
+	defaults Config
+	current  *Config
+	checksum []byte
+	mutex    sync.Mutex
+}
+
+// NewWatcher loads the policy file, the initial content has to be valid
+func NewWatcher(path string, defaults Config) (*Watcher, error) {
+	w := &Watcher{path: path, defaults: defaults}
+	if _, err := w.Reload(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Current returns the last valid policy
+func (w *Watcher) Current() *Config {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.current
+}
+
+// Reload reads the policy file and applies it if its content has changed. An invalid policy is rejected and the last valid one is kept
+func (w *Watcher) Reload() (bool, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	content, err := ioutil.ReadFile(w.path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read policy file %s: %s", w.path, err.Error())
+	}
+	sum := sha256.Sum256(content)
+	if w.current != nil && bytes.Equal(sum[:], w.checksum) {
+		return false, nil
+	}
+	config, err := parse(w.path, content, w.defaults)
+	if err != nil {
+		// remember the checksum so the same broken content is reported only once
+		w.checksum = sum[:]
+		return false, err
+	}
+	if w.current != nil {
+		log.Infof("Policy file %s changed, applying the new policy", w.path)
+	}
+	w.current = config
+	w.checksum = sum[:]
+	return true, nil
+}
@@ -0,0 +1,186 @@
+package policy
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func testDefaults() Config {
+	return Config{
+		HousekeepingInterval: metav1.Duration{Duration: 10 * time.Second},
+		Namespaces:           NamespaceScope{Include: []string{"default"}},
+		Workers:              1,
+		Strategies: Strategies{
+			Spread: SpreadStrategy{Enabled: true, MinReplicaCount: 2, MaxSkew: 1},
+			NodeConditions: NodeConditionsStrategy{
+				Conditions: []NodeCondition{{Type: "MemoryPressure"}},
+			},
+		},
+		MaintenanceWindows: MaintenanceWindows{
+			Windows:    []MaintenanceWindow{{Days: []string{"Sat"}, Start: "01:00", End: "05:00"}},
+			Namespaces: map[string][]MaintenanceWindow{"batch": {{Schedule: "* 0-5 * * *"}}},
+		},
+		Eligibility: Eligibility{ExcludeGroups: []string{"default/db"}},
+	}
+}
+
+func writePolicy(t *testing.T, path, content string) {
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		name    string
+		content string
+		err     string
+		check   func(c *Config) bool
+	}{
+		{
+			name:    "empty file keeps the defaults",
+			content: "",
+			check: func(c *Config) bool {
+				return reflect.DeepEqual(c.Namespaces.Include, []string{"default"}) && c.Strategies.Spread.MaxSkew == 1
+			},
+		},
+		{
+			name:    "fields override the defaults",
+			content: "namespaces:\n  include: [a, b]\nstrategies:\n  spread:\n    maxSkew: 3\nrateLimits:\n  groupCooldown: 5m\n",
+			check: func(c *Config) bool {
+				return reflect.DeepEqual(c.Namespaces.Include, []string{"a", "b"}) && c.Strategies.Spread.MaxSkew == 3 &&
+					c.Strategies.Spread.MinReplicaCount == 2 && c.RateLimits.GroupCooldown.Duration == 5*time.Minute
+			},
+		},
+		{
+			name:    "pod selector is parsed",
+			content: "eligibility:\n  podSelector: app=web\n",
+			check: func(c *Config) bool {
+				return c.Eligibility.Matches(map[string]string{"app": "web"}) && !c.Eligibility.Matches(map[string]string{"app": "db"})
+			},
+		},
+		{name: "malformed yaml", content: "namespaces: [", err: "failed to parse"},
+		{name: "zero max skew", content: "strategies:\n  spread:\n    maxSkew: 0\n", err: "maxSkew must be at least 1"},
+		{name: "zero workers", content: "workers: 0\n", err: "workers must be at least 1"},
+		{name: "empty namespace", content: "namespaces:\n  exclude: [\"\"]\n", err: "namespaces.exclude must not contain an empty namespace"},
+		{name: "invalid pool label", content: "nodePoolLabel: \"a b\"\n", err: "nodePoolLabel is not a valid label key"},
+		{name: "unknown score plugin", content: "scoring:\n  plugins:\n  - name: random\n    weight: 1\n", err: "not a known score plugin"},
+		{name: "duplicated condition", content: "strategies:\n  nodeConditions:\n    conditions:\n    - type: A\n    - type: A\n", err: "is duplicated: A"},
+		{name: "invalid window", content: "maintenanceWindows:\n  windows:\n  - start: \"25:00\"\n    end: \"02:00\"\n", err: "has an invalid start"},
+		{name: "invalid pod selector", content: "eligibility:\n  podSelector: \"a in (\"\n", err: "eligibility.podSelector is invalid"},
+		{
+			name:    "new node age required with the preference",
+			content: "autoscaler:\n  preferNewNodes: true\n",
+			err:     "autoscaler.newNodeMaxAge must be positive",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config, err := parse("policy.yaml", []byte(c.content), testDefaults())
+			if len(c.err) > 0 {
+				if err == nil || !strings.Contains(err.Error(), c.err) {
+					t.Fatalf("expected an error containing %q, got: %v", c.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !c.check(config) {
+				t.Errorf("unexpected policy: %+v", config)
+			}
+		})
+	}
+}
+
+func TestParseDoesNotChangeDefaults(t *testing.T) {
+	defaults := testDefaults()
+	content := `namespaces:
+  include: [evil]
+  exclude: [kube-system]
+strategies:
+  nodeConditions:
+    conditions:
+    - type: DiskPressure
+maintenanceWindows:
+  windows:
+  - days: [Sun]
+    start: "02:00"
+    end: "03:00"
+  namespaces:
+    batch:
+    - schedule: "* 6 * * *"
+eligibility:
+  excludeGroups: [default/evil]
+`
+	if _, err := parse("policy.yaml", []byte(content), defaults); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(defaults, testDefaults()) {
+		t.Errorf("defaults changed by parsing: %+v", defaults)
+	}
+}
+
+func TestWatcherKeepsPolicyOnInvalidReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "policy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "policy.yaml")
+	writePolicy(t, path, "namespaces:\n  include: [web]\nstrategies:\n  spread:\n    maxSkew: 2\n")
+	defaults := testDefaults()
+	w, err := NewWatcher(path, defaults)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	before := w.Current().Clone()
+
+	// the include list fits the backing array of the current one, the invalid skew is found after it is decoded
+	writePolicy(t, path, "namespaces:\n  include: [evil]\n  exclude: [evil]\nstrategies:\n  spread:\n    maxSkew: 0\n")
+	if changed, err := w.Reload(); changed || err == nil {
+		t.Fatalf("invalid policy accepted: changed %v, error %v", changed, err)
+	}
+	if !reflect.DeepEqual(w.Current().Namespaces, before.Namespaces) || w.Current().Strategies.Spread.MaxSkew != 2 {
+		t.Errorf("current policy changed by a rejected reload: %+v", w.Current())
+	}
+	if !reflect.DeepEqual(defaults, testDefaults()) {
+		t.Errorf("defaults changed by a rejected reload: %+v", defaults)
+	}
+
+	// the same broken content is not reported again
+	if changed, err := w.Reload(); changed || err != nil {
+		t.Errorf("unchanged content reloaded: changed %v, error %v", changed, err)
+	}
+
+	writePolicy(t, path, "namespaces:\n  include: [other]\n")
+	if changed, err := w.Reload(); !changed || err != nil {
+		t.Fatalf("valid policy rejected: changed %v, error %v", changed, err)
+	}
+	if got := w.Current(); !reflect.DeepEqual(got.Namespaces.Include, []string{"other"}) || got.Strategies.Spread.MaxSkew != 1 {
+		t.Errorf("unexpected policy after reload: %+v", got)
+	}
+}
+
+func TestNewWatcherRejectsInvalidPolicy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "policy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "policy.yaml")
+	writePolicy(t, path, "housekeepingInterval: 0s\n")
+	if _, err := NewWatcher(path, testDefaults()); err == nil || !strings.Contains(err.Error(), "housekeepingInterval must be positive") {
+		t.Errorf("expected an invalid interval error, got: %v", err)
+	}
+	if _, err := NewWatcher(filepath.Join(dir, "missing.yaml"), testDefaults()); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
@@ -0,0 +1,276 @@
+package policy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaintenanceWindows restricts the evictions to the times when a window is open.
+// An empty list of windows means that the evictions are always allowed
+type MaintenanceWindows struct {
+	// Windows applies to the evictions of every namespace and strategy
+	Windows []MaintenanceWindow `json:"windows,omitempty"`
+	// Namespaces restricts the evictions of the Pods of a namespace further
+	Namespaces map[string][]MaintenanceWindow `json:"namespaces,omitempty"`
+}
+
+// MaintenanceWindow is either a cron schedule of the minutes when the window is open,
+// or a time range of the day on some days of the week
+type MaintenanceWindow struct {
+	// Schedule is a cron expression: minute hour day-of-month month day-of-week, e.g. "* 22-23,0-5 * * 1-5"
+	Schedule string `json:"schedule,omitempty"`
+	// Days lists the days of the week or ranges of them, e.g. Sat or Mon-Fri. Empty means every day
+	Days []string `json:"days,omitempty"`
+	// Start and End are times of the day as HH:MM, the window spans midnight when End is not after Start
+	Start string `json:"start,omitempty"`
+	End   string `json:"end,omitempty"`
+	// TimeZone is an IANA time zone name, e.g. Europe/Budapest. Empty means UTC
+	TimeZone string `json:"timeZone,omitempty"`
+
+	location *time.Location
+	cron     *cronSchedule
+	days     [7]bool
+	start    int
+	end      int
+}
+
+// Open tells whether one of the windows is open at the given time, no windows means always open
+func Open(windows []MaintenanceWindow, now time.Time) bool {
+	if len(windows) == 0 {
+		return true
+	}
+	for i := range windows {
+		if windows[i].Open(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// Open tells whether the window is open at the given time
+func (w *MaintenanceWindow) Open(now time.Time) bool {
+	if w.location == nil {
+		// not validated
+		return false
+	}
+	now = now.In(w.location)
+	if w.cron != nil {
+		return w.cron.matches(now)
+	}
+	minute := now.Hour()*60 + now.Minute()
+	if w.start < w.end {
+		return w.days[now.Weekday()] && minute >= w.start && minute < w.end
+	}
+	// the part after midnight belongs to the window started on the previous day
+	if minute >= w.start {
+		return w.days[now.Weekday()]
+	}
+	return minute < w.end && w.days[(now.Weekday()+6)%7]
+}
+
+func (m MaintenanceWindows) clone() MaintenanceWindows {
+	clone := MaintenanceWindows{Windows: cloneWindows(m.Windows)}
+	if m.Namespaces != nil {
+		clone.Namespaces = make(map[string][]MaintenanceWindow, len(m.Namespaces))
+		for namespace, windows := range m.Namespaces {
+			clone.Namespaces[namespace] = cloneWindows(windows)
+		}
+	}
+	return clone
+}
+
+// The parsed schedules are shared, they are replaced but never changed by the validation
+func cloneWindows(windows []MaintenanceWindow) []MaintenanceWindow {
+	if windows == nil {
+		return nil
+	}
+	clone := make([]MaintenanceWindow, len(windows))
+	for i := range windows {
+		clone[i] = windows[i]
+		clone[i].Days = append([]string(nil), windows[i].Days...)
+	}
+	return clone
+}
+
+func (m *MaintenanceWindows) validate(field string) error {
+	if err := validateWindows(field+".windows", m.Windows); err != nil {
+		return err
+	}
+	for namespace, windows := range m.Namespaces {
+		if len(namespace) == 0 {
+			return fmt.Errorf("%s.namespaces must not contain an empty namespace", field)
+		}
+		if err := validateWindows(field+".namespaces."+namespace, windows); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateWindows(field string, windows []MaintenanceWindow) error {
+	for i := range windows {
+		if err := windows[i].validate(); err != nil {
+			return fmt.Errorf("%s[%d] %s", field, i, err.Error())
+		}
+	}
+	return nil
+}
+
+func (w *MaintenanceWindow) validate() error {
+	location, err := time.LoadLocation(w.TimeZone)
+	if err != nil {
+		return fmt.Errorf("has an invalid timeZone: %s", err.Error())
+	}
+	if len(w.Schedule) > 0 {
+		if len(w.Days) > 0 || len(w.Start) > 0 || len(w.End) > 0 {
+			return fmt.Errorf("must have either a schedule or days, start and end")
+		}
+		cron, err := parseCron(w.Schedule)
+		if err != nil {
+			return fmt.Errorf("has an invalid schedule: %s", err.Error())
+		}
+		w.location, w.cron = location, cron
+		return nil
+	}
+	if w.start, err = parseTimeOfDay(w.Start); err != nil {
+		return fmt.Errorf("has an invalid start: %s", err.Error())
+	}
+	if w.end, err = parseTimeOfDay(w.End); err != nil {
+		return fmt.Errorf("has an invalid end: %s", err.Error())
+	}
+	if w.start == w.end {
+		return fmt.Errorf("must not start and end at the same time: %s", w.Start)
+	}
+	w.days = [7]bool{}
+	if len(w.Days) == 0 {
+		w.days = [7]bool{true, true, true, true, true, true, true}
+	}
+	for _, days := range w.Days {
+		if err := parseDays(days, &w.days); err != nil {
+			return fmt.Errorf("has invalid days: %s", err.Error())
+		}
+	}
+	w.location = location
+	return nil
+}
+
+// Minutes since midnight of a HH:MM time of the day
+func parseTimeOfDay(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a HH:MM time", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// A day of the week or a range of them like Mon-Fri or Fri-Mon
+func parseDays(value string, days *[7]bool) error {
+	bounds := strings.SplitN(value, "-", 2)
+	first, found := weekdays[strings.ToLower(bounds[0])]
+	if !found {
+		return fmt.Errorf("%q is not a day of the week", bounds[0])
+	}
+	last := first
+	if len(bounds) == 2 {
+		if last, found = weekdays[strings.ToLower(bounds[1])]; !found {
+			return fmt.Errorf("%q is not a day of the week", bounds[1])
+		}
+	}
+	for day := first; ; day = (day + 1) % 7 {
+		days[day] = true
+		if day == last {
+			return nil
+		}
+	}
+}
+
+// cronSchedule is a parsed standard 5 field cron expression
+type cronSchedule struct {
+	minutes, hours, daysOfMonth, months, daysOfWeek []bool
+	// like in cron, a day field starting with * is unrestricted even with a step, e.g. */2: then both day fields have to match,
+	// when both of them are restricted either of them has to match
+	anyDayOfMonth, anyDayOfWeek bool
+}
+
+func parseCron(expression string) (*cronSchedule, error) {
+	fields := strings.Fields(expression)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("%q must have 5 fields: minute hour day-of-month month day-of-week", expression)
+	}
+	var err error
+	c := &cronSchedule{anyDayOfMonth: strings.HasPrefix(fields[2], "*"), anyDayOfWeek: strings.HasPrefix(fields[4], "*")}
+	if c.minutes, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, err
+	}
+	if c.hours, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, err
+	}
+	if c.daysOfMonth, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, err
+	}
+	if c.months, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, err
+	}
+	// both 0 and 7 are Sunday
+	if c.daysOfWeek, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, err
+	}
+	c.daysOfWeek[0] = c.daysOfWeek[0] || c.daysOfWeek[7]
+	return c, nil
+}
+
+// A comma separated list of *, values and ranges, each of them with an optional /step
+func parseCronField(field string, min, max int) ([]bool, error) {
+	values := make([]bool, max+1)
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step < 1 {
+				return nil, fmt.Errorf("invalid step in %q", part)
+			}
+			part = part[:i]
+		}
+		first, last := min, max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if first, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, fmt.Errorf("invalid value in %q", field)
+			}
+			last = first
+			if len(bounds) == 2 {
+				if last, err = strconv.Atoi(bounds[1]); err != nil {
+					return nil, fmt.Errorf("invalid value in %q", field)
+				}
+			} else if step > 1 {
+				last = max
+			}
+		}
+		if first < min || last > max || first > last {
+			return nil, fmt.Errorf("%q is out of the range %d-%d", part, min, max)
+		}
+		for value := first; value <= last; value += step {
+			values[value] = true
+		}
+	}
+	return values, nil
+}
+
+func (c *cronSchedule) matches(t time.Time) bool {
+	if !c.minutes[t.Minute()] || !c.hours[t.Hour()] || !c.months[t.Month()] {
+		return false
+	}
+	dayOfMonth, dayOfWeek := c.daysOfMonth[t.Day()], c.daysOfWeek[t.Weekday()]
+	if c.anyDayOfMonth || c.anyDayOfWeek {
+		return dayOfMonth && dayOfWeek
+	}
+	return dayOfMonth || dayOfWeek
+}
@@ -0,0 +1,151 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMaintenanceWindowOpen(t *testing.T) {
+	// 2018-03-05 is a Monday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2018, 3, day, hour, minute, 0, 0, time.UTC)
+	}
+	cases := []struct {
+		name   string
+		window MaintenanceWindow
+		open   []time.Time
+		closed []time.Time
+	}{
+		{
+			name:   "time range on every day",
+			window: MaintenanceWindow{Start: "01:00", End: "05:00"},
+			open:   []time.Time{at(5, 1, 0), at(10, 4, 59)},
+			closed: []time.Time{at(5, 0, 59), at(5, 5, 0)},
+		},
+		{
+			name:   "time range on some days",
+			window: MaintenanceWindow{Days: []string{"Sat", "mon-tue"}, Start: "01:00", End: "05:00"},
+			open:   []time.Time{at(3, 2, 0), at(5, 2, 0), at(6, 2, 0)},
+			closed: []time.Time{at(4, 2, 0), at(7, 2, 0)},
+		},
+		{
+			name:   "range of days over the end of the week",
+			window: MaintenanceWindow{Days: []string{"Fri-Mon"}, Start: "01:00", End: "05:00"},
+			open:   []time.Time{at(2, 2, 0), at(4, 2, 0), at(5, 2, 0)},
+			closed: []time.Time{at(6, 2, 0), at(8, 2, 0)},
+		},
+		{
+			name:   "time range over midnight belongs to the day it starts",
+			window: MaintenanceWindow{Days: []string{"Fri"}, Start: "22:00", End: "02:00"},
+			open:   []time.Time{at(2, 22, 0), at(3, 1, 59)},
+			closed: []time.Time{at(2, 1, 0), at(3, 2, 0), at(3, 22, 0)},
+		},
+		{
+			name:   "time range in a time zone",
+			window: MaintenanceWindow{Start: "01:00", End: "05:00", TimeZone: "America/New_York"},
+			open:   []time.Time{at(5, 6, 0), at(5, 9, 59)},
+			closed: []time.Time{at(5, 2, 0), at(5, 10, 0)},
+		},
+		{
+			name:   "cron schedule",
+			window: MaintenanceWindow{Schedule: "*/15 22-23,0-5 * * 1-5"},
+			open:   []time.Time{at(5, 22, 0), at(6, 5, 45)},
+			closed: []time.Time{at(5, 22, 1), at(5, 6, 0), at(4, 22, 0)},
+		},
+		{
+			name:   "cron schedule with 7 as Sunday",
+			window: MaintenanceWindow{Schedule: "* * * * 7"},
+			open:   []time.Time{at(4, 12, 0)},
+			closed: []time.Time{at(5, 12, 0)},
+		},
+		{
+			name:   "cron schedule with both day fields matches either of them",
+			window: MaintenanceWindow{Schedule: "* * 1 * 1"},
+			open:   []time.Time{at(1, 12, 0), at(5, 12, 0)},
+			closed: []time.Time{at(2, 12, 0)},
+		},
+		{
+			name:   "cron day field with a step is unrestricted",
+			window: MaintenanceWindow{Schedule: "* * */1 * 1"},
+			open:   []time.Time{at(5, 12, 0), at(12, 12, 0)},
+			closed: []time.Time{at(1, 12, 0), at(2, 12, 0)},
+		},
+		{
+			name:   "cron day fields with a step have to match both",
+			window: MaintenanceWindow{Schedule: "* * */2 * 1"},
+			open:   []time.Time{at(5, 12, 0)},
+			closed: []time.Time{at(1, 12, 0), at(12, 12, 0)},
+		},
+		{
+			name:   "cron schedule in a time zone",
+			window: MaintenanceWindow{Schedule: "* 1-4 * * *", TimeZone: "Europe/Budapest"},
+			open:   []time.Time{at(5, 0, 0), at(5, 3, 59)},
+			closed: []time.Time{at(4, 23, 59), at(5, 4, 0)},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if err := c.window.validate(); err != nil {
+				t.Fatalf("invalid window: %v", err)
+			}
+			for _, now := range c.open {
+				if !c.window.Open(now) {
+					t.Errorf("expected the window to be open at %s", now.Format(time.RFC1123))
+				}
+			}
+			for _, now := range c.closed {
+				if c.window.Open(now) {
+					t.Errorf("expected the window to be closed at %s", now.Format(time.RFC1123))
+				}
+			}
+		})
+	}
+}
+
+func TestMaintenanceWindowValidate(t *testing.T) {
+	cases := []struct {
+		name   string
+		window MaintenanceWindow
+		err    string
+	}{
+		{name: "unknown time zone", window: MaintenanceWindow{Start: "01:00", End: "02:00", TimeZone: "Mars/Olympus"}, err: "invalid timeZone"},
+		{name: "schedule with a time range", window: MaintenanceWindow{Schedule: "* * * * *", Start: "01:00"}, err: "either a schedule or days"},
+		{name: "missing start", window: MaintenanceWindow{End: "02:00"}, err: "invalid start"},
+		{name: "invalid end", window: MaintenanceWindow{Start: "01:00", End: "2am"}, err: "invalid end"},
+		{name: "empty time range", window: MaintenanceWindow{Start: "01:00", End: "01:00"}, err: "must not start and end at the same time"},
+		{name: "unknown day", window: MaintenanceWindow{Days: []string{"Mon-Funday"}, Start: "01:00", End: "02:00"}, err: "\"Funday\" is not a day of the week"},
+		{name: "too few cron fields", window: MaintenanceWindow{Schedule: "* * * *"}, err: "must have 5 fields"},
+		{name: "cron value out of range", window: MaintenanceWindow{Schedule: "* 24 * * *"}, err: "out of the range 0-23"},
+		{name: "reversed cron range", window: MaintenanceWindow{Schedule: "* * * 12-1 *"}, err: "out of the range 1-12"},
+		{name: "invalid cron step", window: MaintenanceWindow{Schedule: "*/0 * * * *"}, err: "invalid step"},
+		{name: "invalid cron value", window: MaintenanceWindow{Schedule: "* * * * Mon"}, err: "invalid value"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.window.validate()
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Errorf("expected an error containing %q, got: %v", c.err, err)
+			}
+		})
+	}
+}
+
+func TestOpenWithoutWindows(t *testing.T) {
+	now := time.Date(2018, 3, 5, 12, 0, 0, 0, time.UTC)
+	if !Open(nil, now) {
+		t.Error("expected no windows to be always open")
+	}
+	windows := []MaintenanceWindow{{Start: "01:00", End: "02:00"}, {Start: "11:00", End: "13:00"}}
+	if Open(windows, now) {
+		t.Error("expected the windows which were not validated to be closed")
+	}
+	for i := range windows {
+		if err := windows[i].validate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !Open(windows, now) {
+		t.Error("expected the second window to be open")
+	}
+}
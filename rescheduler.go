@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/hortonworks/pod-rescheduler/audit"
+	"github.com/hortonworks/pod-rescheduler/cluster"
+	"github.com/hortonworks/pod-rescheduler/metrics"
+	"github.com/hortonworks/pod-rescheduler/notify"
+	"github.com/hortonworks/pod-rescheduler/pkg/engine"
+	"github.com/hortonworks/pod-rescheduler/policy"
+	utils "github.com/hortonworks/pod-rescheduler/utils"
+	corev1 "k8s.io/api/core/v1"
+)
+
+type rescheduler struct {
+	// cancelled when the rescheduler shuts down
+	ctx                context.Context
+	client             cluster.Client
+	defaultPolicy      *policy.Config
+	policyWatcher      *policy.Watcher
+	podsBeingProcessed *utils.PodSet
+	lastMoveOfGroup    map[string]time.Time
+	health             *health
+	events             *eventRecorder
+	auditLog           *audit.Log
+	cycleID            string
+	now                func() time.Time
+	waitForReadiness   func(e eviction)
+	readinessWaits     sync.WaitGroup
+	misses             *targetMisses
+	// the quiet decisions are audited as well, e.g. the balanced groups
+	auditQuiet bool
+	// node changes which trigger a cycle before the housekeeping interval passes, nil when they are not watched
+	triggers <-chan trigger
+	notifier *notify.Notifier
+	// the source nodes of the evictions are tainted, so the replacements are scheduled elsewhere.
+	// The identity is the value of the taints, the stale taints of the other instances are not removed
+	steering   bool
+	steeringID string
+	// warns once when only the Pods of the namespace scope can be listed
+	scopedPodsWarning sync.Once
+}
+
+func newRescheduler(ctx context.Context, client cluster.Client, defaultPolicy *policy.Config, policyWatcher *policy.Watcher) *rescheduler {
+	r := &rescheduler{
+		ctx:                ctx,
+		client:             &retryingClient{&instrumentedClient{client}, *kubeAPIRetries, *kubeAPIRetryBackoff},
+		defaultPolicy:      defaultPolicy,
+		policyWatcher:      policyWatcher,
+		podsBeingProcessed: utils.NewPodSet(),
+		lastMoveOfGroup:    make(map[string]time.Time),
+		health:             newHealth(*livenessMultiplier),
+		events:             newNoopEventRecorder(),
+		now:                time.Now,
+		misses:             newTargetMisses(*targetMissExpiry),
+	}
+	r.waitForReadiness = func(e eviction) {
+		r.readinessWaits.Add(1)
+		go func() {
+			defer r.readinessWaits.Done()
+			r.waitForPodReadiness(e)
+		}()
+	}
+	return r
+}
+
+// The policy read from the policy file or the one built from the flags
+func (r *rescheduler) policy() *policy.Config {
+	if r.policyWatcher != nil {
+		return r.policyWatcher.Current()
+	}
+	return r.defaultPolicy
+}
+
+// Poll the policy file at the start of every housekeeping cycle, so a change is applied by the next cycle.
+// An invalid policy is not applied
+func (r *rescheduler) reloadPolicy() {
+	if r.policyWatcher == nil {
+		return
+	}
+	if _, err := r.policyWatcher.Reload(); err != nil {
+		log.Errorf("Policy reload failed, keeping the last valid policy: %s", err.Error())
+	}
+}
+
+// Run housekeeping cycles until the context is cancelled, the current cycle and the readiness waits are finished before returning
+func (r *rescheduler) run() {
+	for {
+		t := trigger{reason: triggerInterval}
+		select {
+		case <-r.ctx.Done():
+			log.Info("Stopping housekeeping, waiting for the readiness checks to finish")
+			r.readinessWaits.Wait()
+			return
+		case <-time.After(r.policy().HousekeepingInterval.Duration):
+		case t = <-r.triggers:
+			log.WithFields(log.Fields{"node": t.node, "trigger": t.reason}).Info("Housekeeping cycle triggered by a node change")
+		}
+		r.reloadPolicy()
+		if _, err := r.runOnce(t.reason); err != nil {
+			log.WithField("trigger", t.reason).Errorf("Housekeeping cycle failed, no Pod is evicted: %s", err.Error())
+			r.notify(notify.Notification{Kind: notify.CycleFailed, Severity: notify.Error, Reason: t.reason, Message: err.Error()}, nil)
+		}
+	}
+}
+
+// A single housekeeping cycle: observe the cluster, plan the moves and execute them. The trigger tells why the cycle runs
+func (r *rescheduler) runOnce(trigger string) ([]engine.Action, error) {
+	start := time.Now()
+	r.cycleID = r.now().UTC().Format("20060102-150405.000")
+	ctx, cancel := r.cycleContext()
+	defer cancel()
+	p := r.policy()
+	snapshot, err := r.observe(ctx, p)
+	var actions []engine.Action
+	if err == nil {
+		actions = r.plan(snapshot, p)
+		engine.Execute(ctx, actions, r.client, r, p, r.now)
+		r.updateGroupMetrics(p)
+	}
+	metrics.Cycle(start, trigger, err)
+	r.health.cycleCompleted()
+	return actions, err
+}
+
+// Every API call of a cycle has to complete before the cycle deadline
+func (r *rescheduler) cycleContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(r.ctx, *cycleDeadline)
+}
+
+// List the schedulable nodes and the Pods running on them
+func (r *rescheduler) observe(ctx context.Context, p *policy.Config) (*engine.Snapshot, error) {
+	snapshot, err := engine.Observe(ctx, r.client, &p.Namespaces)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot.ScopedPodsOnly {
+		r.scopedPodsWarning.Do(func() {
+			log.Warn("Pods of every namespace cannot be listed, only the ones of the namespace scope are counted in the capacity of the nodes. Grant a ClusterRole to list the Pods of every namespace")
+		})
+	}
+	logPods(snapshot.Groups())
+	metrics.GroupSkew(engine.GroupSkews(snapshot, p.NodePoolLabel))
+	return snapshot, nil
+}
+
+// The decisions of the cycle, computed without touching the cluster
+func (r *rescheduler) plan(snapshot *engine.Snapshot, p *policy.Config) []engine.Action {
+	if !p.Strategies.Spread.Enabled {
+		log.Info("Spread strategy is disabled by the policy")
+	}
+	return engine.Plan(snapshot, r.state(), p)
+}
+
+func (r *rescheduler) state() *engine.State {
+	return &engine.State{
+		InFlight:     r.podsBeingProcessed.Groups(),
+		LastMoves:    r.lastMoveOfGroup,
+		TargetMisses: r.misses.current(r.now()),
+		Now:          r.now(),
+	}
+}
+
+// Skipped reports a Pod group or a Pod which is not moved in this cycle
+func (r *rescheduler) Skipped(action engine.Action) {
+	entry := decisionLog(action.Strategy, action.Group, action.Pod).WithField("reason", action.Reason)
+	for key, value := range action.Inputs {
+		entry = entry.WithField(key, value)
+	}
+	decision := audit.Skipped
+	if action.Type == engine.ActionDefer {
+		decision = audit.Deferred
+	}
+	record := audit.Record{Decision: decision, Strategy: action.Strategy, Group: action.Group, Reason: action.Reason, Inputs: action.Inputs}
+	if action.Quiet() {
+		entry.Debug("No action required")
+		if r.auditQuiet {
+			r.audit(record, action.Pod)
+		}
+		return
+	}
+	if len(action.Group) > 0 {
+		entry.Infof("Pod group is %s", decision)
+	} else {
+		entry.Infof("Pods of the node are %s", decision)
+	}
+	metrics.Skipped(action.Reason)
+	r.audit(record, action.Pod)
+	if action.Reason == engine.ReasonNoTargetNode {
+		r.events.podSkipped(action.Pod, eventReasonNoCandidateNode, action.Strategy, "no candidate node without a Pod of the same group")
+		r.notify(notify.Notification{
+			Kind:     notify.Skipped,
+			Severity: notify.Warning,
+			Strategy: action.Strategy,
+			Group:    action.Group,
+			Reason:   action.Reason,
+			Message:  "no candidate node can run the Pod",
+		}, action.Pod)
+	}
+}
+
+// Evicted reports the deletion of a Pod in order to reschedule it and tracks its replacement
+func (r *rescheduler) Evicted(action engine.Action, err error) {
+	pod := action.Pod
+	metrics.Eviction(action.Strategy, pod.Namespace, action.Inputs["pool"], err)
+	record := audit.Record{
+		Decision:   audit.Evicted,
+		Strategy:   action.Strategy,
+		Group:      action.Group,
+		TargetNode: action.Target,
+		Reason:     action.Reason,
+		Inputs:     action.Inputs,
+		Result:     metrics.Success,
+	}
+	if err != nil {
+		record.Decision = audit.Failed
+		record.Result = err.Error()
+	}
+	r.audit(record, pod)
+	entry := decisionLog(action.Strategy, action.Group, pod).WithFields(log.Fields{
+		"target": action.Target,
+		"reason": action.Reason,
+	})
+	n := notify.Notification{Strategy: action.Strategy, Group: action.Group, TargetNode: action.Target, Reason: action.Reason}
+	if err != nil {
+		entry.WithField("error", err.Error()).Error("Failed to delete Pod")
+		n.Kind, n.Severity, n.Message = notify.EvictionFailed, notify.Error, err.Error()
+		r.notify(n, pod)
+		r.unsteer(action)
+		return
+	}
+	// the replacement is not pinned to the target, the scheduler places it: a missed target is penalized for the group
+	entry.Info("Deleted Pod in order to reschedule it to another node")
+	n.Kind, n.Severity, n.Message = notify.Evicted, notify.Info, "Pod is evicted in order to reschedule it to "+action.Target
+	r.notify(n, pod)
+	r.events.podMoved(pod, action.Target, action.Strategy, action.Reason)
+	r.lastMoveOfGroup[action.Group] = r.now()
+	r.podsBeingProcessed.Add(pod)
+	r.waitForReadiness(eviction{action: action, at: r.now(), cycleID: r.cycleID})
+}
+
+// Release the resources which are not freed by the exit of the process
+func (r *rescheduler) close() {
+	r.closeNotifier()
+	if r.auditLog != nil {
+		if err := r.auditLog.Close(); err != nil {
+			log.Errorf("Failed to close the audit log: %s", err.Error())
+		}
+	}
+}
+
+// Append the decision to the audit log if it is enabled
+func (r *rescheduler) audit(record audit.Record, pod *corev1.Pod) {
+	if r.auditLog == nil {
+		return
+	}
+	if len(record.CycleID) == 0 {
+		record.CycleID = r.cycleID
+	}
+	if len(record.Strategy) == 0 {
+		record.Strategy = engine.SpreadStrategy
+	}
+	if pod != nil {
+		record.Namespace = pod.Namespace
+		record.Pod = pod.Name
+		record.PodUID = string(pod.UID)
+		record.SourceNode = pod.Spec.NodeName
+	}
+	r.auditLog.Record(record)
+}
+
+// Structured log entry of a decision about a Pod group, the Pod is optional
+func decisionLog(strategy, group string, pod *corev1.Pod) *log.Entry {
+	fields := log.Fields{
+		"strategy": strategy,
+		"group":    group,
+	}
+	if pod != nil {
+		fields["namespace"] = pod.Namespace
+		fields["pod"] = pod.Name
+		fields["node"] = pod.Spec.NodeName
+	}
+	return log.WithFields(fields)
+}
+
+// Forget the groups whose cooldown is over and publish the number of groups which cannot be moved yet
+func (r *rescheduler) updateGroupMetrics(p *policy.Config) {
+	state := r.state()
+	for group := range r.lastMoveOfGroup {
+		if !state.InCooldown(group, p.RateLimits.GroupCooldown.Duration) {
+			delete(r.lastMoveOfGroup, group)
+		}
+	}
+	metrics.GroupsInCooldown(len(r.lastMoveOfGroup))
+	metrics.GroupsInFlight(r.podsBeingProcessed.GroupCount())
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hortonworks/pod-rescheduler/audit"
+	"github.com/hortonworks/pod-rescheduler/pkg/engine"
+)
+
+func TestSkippedAuditsQuietDecisionsOnlyWhenEnabled(t *testing.T) {
+	actions := []engine.Action{
+		{Type: engine.ActionSkip, Strategy: engine.SpreadStrategy, Group: "default/web", Reason: engine.ReasonBalanced},
+		{Type: engine.ActionDefer, Strategy: engine.SpreadStrategy, Group: "default/db", Reason: engine.ReasonCooldown},
+	}
+	cases := []struct {
+		name  string
+		quiet bool
+		want  []string
+	}{
+		{name: "default", want: []string{`"decision":"deferred"`}},
+		{name: "quiet decisions", quiet: true, want: []string{`"decision":"skipped"`, `"decision":"deferred"`}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "audit")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			path := filepath.Join(dir, "audit.log")
+			r, _ := testRescheduler(nil, nil)
+			if r.auditLog, err = audit.NewLog(path, 0, 0); err != nil {
+				t.Fatal(err)
+			}
+			r.auditQuiet = c.quiet
+			for _, action := range actions {
+				r.Skipped(action)
+			}
+			r.auditLog.Close()
+
+			var out bytes.Buffer
+			if err := audit.Query(path, 0, &audit.Filter{}, &out); err != nil {
+				t.Fatal(err)
+			}
+			records := strings.Split(strings.TrimSpace(out.String()), "\n")
+			if len(records) != len(c.want) {
+				t.Fatalf("expected %d records, got: %v", len(c.want), records)
+			}
+			for i, want := range c.want {
+				if !strings.Contains(records[i], want) {
+					t.Errorf("expected %s in the record %s", want, records[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/hortonworks/pod-rescheduler/cluster"
+	"github.com/hortonworks/pod-rescheduler/metrics"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// The waits between the retries are up to this much longer than the backoff, so the clients do not retry in lockstep
+const retryJitter = 0.5
+
+// retryingClient retries the idempotent API calls failed with a transient error, with an exponential backoff and jitter.
+// The Pods are not deleted again: the replacement of a StatefulSet Pod has the same name
+type retryingClient struct {
+	cluster.Client
+	retries int
+	backoff time.Duration
+}
+
+func (c *retryingClient) ListNodes(ctx context.Context) ([]corev1.Node, error) {
+	var nodes []corev1.Node
+	err := c.retry(ctx, "list", "nodes", func() (err error) {
+		nodes, err = c.Client.ListNodes(ctx)
+		return err
+	})
+	return nodes, err
+}
+
+func (c *retryingClient) WatchNodes(ctx context.Context) (watch.Interface, error) {
+	var watcher watch.Interface
+	err := c.retry(ctx, "watch", "nodes", func() (err error) {
+		watcher, err = c.Client.WatchNodes(ctx)
+		return err
+	})
+	return watcher, err
+}
+
+func (c *retryingClient) ListPods(ctx context.Context, namespace, node string) ([]corev1.Pod, error) {
+	var pods []corev1.Pod
+	err := c.retry(ctx, "list", "pods", func() (err error) {
+		pods, err = c.Client.ListPods(ctx, namespace, node)
+		return err
+	})
+	return pods, err
+}
+
+func (c *retryingClient) GetPod(ctx context.Context, namespace, name string) (*corev1.Pod, error) {
+	var pod *corev1.Pod
+	err := c.retry(ctx, "get", "pods", func() (err error) {
+		pod, err = c.Client.GetPod(ctx, namespace, name)
+		return err
+	})
+	return pod, err
+}
+
+func (c *retryingClient) GetNode(ctx context.Context, name string) (*corev1.Node, error) {
+	var node *corev1.Node
+	err := c.retry(ctx, "get", "nodes", func() (err error) {
+		node, err = c.Client.GetNode(ctx, name)
+		return err
+	})
+	return node, err
+}
+
+// An update is retried with the same resource version, so a repeated update which was applied already fails with a conflict
+func (c *retryingClient) UpdateNode(ctx context.Context, node *corev1.Node) error {
+	return c.retry(ctx, "update", "nodes", func() error {
+		return c.Client.UpdateNode(ctx, node)
+	})
+}
+
+func (c *retryingClient) SetUnschedulable(ctx context.Context, node string, unschedulable bool) error {
+	return c.retry(ctx, "patch", "nodes", func() error {
+		return c.Client.SetUnschedulable(ctx, node, unschedulable)
+	})
+}
+
+func (c *retryingClient) ServerVersion(ctx context.Context) (string, error) {
+	var version string
+	err := c.retry(ctx, "get", "version", func() (err error) {
+		version, err = c.Client.ServerVersion(ctx)
+		return err
+	})
+	return version, err
+}
+
+// Call the function until it succeeds, fails with a permanent error, the retries run out or the context is done
+func (c *retryingClient) retry(ctx context.Context, verb, resource string, call func() error) error {
+	backoff := c.backoff
+	for attempt := 0; ; attempt++ {
+		err := call()
+		if err == nil || attempt >= c.retries || !transient(err) || ctx.Err() != nil {
+			return err
+		}
+		delay := wait.Jitter(backoff, retryJitter)
+		log.WithFields(log.Fields{"verb": verb, "resource": resource}).Warningf("Kubernetes API call failed, retrying in %v: %s",
+			delay.Truncate(time.Millisecond), err.Error())
+		metrics.APIRetry(verb, resource)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		backoff *= 2
+	}
+}
+
+// The API server is overloaded or unavailable, or it cannot be reached. The other errors would fail again
+func transient(err error) bool {
+	status, ok := err.(apierrors.APIStatus)
+	if !ok {
+		return true
+	}
+	switch status.Status().Code {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// The context is cancelled on SIGTERM or SIGINT, the process exits if it does not stop within the grace period
+func shutdownContext(gracePeriod time.Duration) context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+	go func() {
+		sig := <-signals
+		log.Infof("Received %s, shutting down within %v", sig, gracePeriod)
+		cancel()
+		time.AfterFunc(gracePeriod, func() {
+			log.Errorf("Could not stop within the grace period of %v, exiting", gracePeriod)
+			os.Exit(1)
+		})
+	}()
+	return ctx
+}
+
+func stopHTTPServer(server *http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Errorf("Failed to stop the HTTP server: %s", err.Error())
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/hortonworks/pod-rescheduler/cluster"
+	"github.com/hortonworks/pod-rescheduler/pkg/engine"
+	"github.com/hortonworks/pod-rescheduler/policy"
+	utils "github.com/hortonworks/pod-rescheduler/utils"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// A Pod evicted in a simulated housekeeping cycle and the node where its replacement was placed
+type simulatedMove struct {
+	Cycle       int    `json:"cycle"`
+	Group       string `json:"group"`
+	Namespace   string `json:"namespace"`
+	Pod         string `json:"pod"`
+	Node        string `json:"node"`
+	Target      string `json:"target"`
+	Placed      string `json:"placed,omitempty"`
+	Replacement string `json:"replacement,omitempty"`
+	Reason      string `json:"reason"`
+}
+
+// The number of Pods of a group on a node at the end of the simulation
+type groupDistribution struct {
+	Group string `json:"group"`
+	Node  string `json:"node"`
+	Pods  int    `json:"pods"`
+}
+
+type simulationResult struct {
+	Moves        []simulatedMove     `json:"moves"`
+	Distribution []groupDistribution `json:"distribution"`
+}
+
+// pod-rescheduler snapshot: write the state of the cluster to the snapshot file or to the standard output
+func snapshotCommand(clientSet kubernetes.Interface, namespace, path string) int {
+	snapshot, err := cluster.Capture(clientSet, namespace)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot capture the snapshot: %s\n", err.Error())
+		return 1
+	}
+	if snapshot.ScopedPodsOnly {
+		log.Warnf("Pods of every namespace cannot be listed, only the ones of namespace %s are captured", namespace)
+	}
+	var out io.Writer = os.Stdout
+	if len(path) > 0 {
+		file, err := os.Create(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Cannot create the snapshot file: %s\n", err.Error())
+			return 1
+		}
+		defer file.Close()
+		out = file
+	}
+	if err := snapshot.Write(out); err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot write the snapshot: %s\n", err.Error())
+		return 1
+	}
+	log.Infof("Snapshot of %d nodes and %d pods is written", len(snapshot.Nodes), len(snapshot.Pods))
+	return 0
+}
+
+// pod-rescheduler simulate --snapshot <file>: run housekeeping cycles against the snapshot in memory.
+// The time starts at the capture of the snapshot and it advances by a housekeeping interval in every cycle,
+// the evicted Pods are replaced and placed by a simulated scheduler at the end of the cycle
+func simulateCommand(defaults *policy.Config, policyWatcher *policy.Watcher, path string, cycles int, format string) int {
+	if len(path) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: pod-rescheduler simulate --snapshot <file> [flags]")
+		return 2
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot open the snapshot: %s\n", err.Error())
+		return 1
+	}
+	snapshot, err := cluster.ReadSnapshot(file)
+	file.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot read the snapshot: %s\n", err.Error())
+		return 1
+	}
+
+	memory := cluster.NewMemory(snapshot)
+	r := newRescheduler(context.Background(), memory, defaults, policyWatcher)
+	now := snapshot.CapturedAt.Time
+	r.now = func() time.Time { return now }
+	// the replacements are ready as soon as they are placed
+	r.waitForReadiness = func(e eviction) {}
+
+	result := simulationResult{Moves: []simulatedMove{}}
+	for cycle := 1; cycle <= cycles; cycle++ {
+		now = now.Add(r.policy().HousekeepingInterval.Duration)
+		actions, err := r.runOnce(triggerInterval)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Simulated cycle %d failed: %s\n", cycle, err.Error())
+			return 1
+		}
+		planned := make(map[types.UID]engine.Action)
+		for _, action := range actions {
+			if action.Type == engine.ActionMove {
+				planned[action.Pod.UID] = action
+			}
+		}
+		for _, placement := range memory.Schedule(now) {
+			r.podsBeingProcessed.Remove(&placement.Evicted)
+			m, found := planned[placement.Evicted.UID]
+			if found {
+				r.placementVerified(eviction{action: m, at: now, cycleID: r.cycleID}, placement.Replacement)
+			}
+			simulated := simulatedMove{
+				Cycle:     cycle,
+				Group:     m.Group,
+				Namespace: placement.Evicted.Namespace,
+				Pod:       placement.Evicted.Name,
+				Node:      placement.Evicted.Spec.NodeName,
+				Target:    m.Target,
+				Reason:    m.Reason,
+			}
+			if placement.Replacement != nil {
+				simulated.Placed = placement.Replacement.Spec.NodeName
+				simulated.Replacement = placement.Replacement.Name
+			}
+			result.Moves = append(result.Moves, simulated)
+		}
+	}
+	result.Distribution = distribution(memory.Pods())
+
+	if format != outputTable {
+		if err := printOutput(os.Stdout, format, result, nil, nil, 0); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			return 2
+		}
+		return 0
+	}
+	printOutput(os.Stdout, format, nil, []string{"CYCLE", "POD", "NODE", "TARGET", "PLACED", "REPLACEMENT", "REASON"}, func(row int) []string {
+		m := result.Moves[row]
+		return []string{fmt.Sprint(m.Cycle), m.Namespace + "/" + m.Pod, m.Node, m.Target, m.Placed, m.Replacement, m.Reason}
+	}, len(result.Moves))
+	fmt.Fprintln(os.Stdout)
+	printOutput(os.Stdout, format, nil, []string{"GROUP", "NODE", "PODS"}, func(row int) []string {
+		d := result.Distribution[row]
+		return []string{d.Group, d.Node, fmt.Sprint(d.Pods)}
+	}, len(result.Distribution))
+	return 0
+}
+
+// The number of Pods of every group per node, sorted by group and node
+func distribution(pods []corev1.Pod) []groupDistribution {
+	counts := make(map[string]map[string]int)
+	for _, pod := range pods {
+		group := utils.GetPodGroupName(&pod)
+		if group == nil {
+			continue
+		}
+		if counts[*group] == nil {
+			counts[*group] = make(map[string]int)
+		}
+		counts[*group][pod.Spec.NodeName]++
+	}
+	result := []groupDistribution{}
+	for group, nodes := range counts {
+		for node, count := range nodes {
+			result = append(result, groupDistribution{Group: group, Node: node, Pods: count})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Group != result[j].Group {
+			return result[i].Group < result[j].Group
+		}
+		return result[i].Node < result[j].Node
+	})
+	return result
+}
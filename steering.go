@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/hortonworks/pod-rescheduler/pkg/engine"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// How many times a taint update is retried when the node was changed meanwhile
+const maxTaintConflicts = 5
+
+// The steering taint of the eviction of the Pod, its key is unique to the Pod and its value is the identity of the rescheduler
+func (r *rescheduler) steeringTaint(pod *corev1.Pod) corev1.Taint {
+	return corev1.Taint{Key: engine.SteeringTaintPrefix + string(pod.UID), Value: r.steeringID, Effect: corev1.TaintEffectPreferNoSchedule}
+}
+
+// The identity of the steering taints by default. It is stable, so the taints left by a crashed instance are removed
+// by the next one, e.g. by the replacement Pod of the Deployment or the next run of a CronJob
+const defaultSteeringID = "pod-rescheduler"
+
+// PrepareEviction taints the source node of the move with a PreferNoSchedule taint when the replacements are steered,
+// so the scheduler prefers the other nodes. The Pod is evicted even if the node cannot be tainted
+func (r *rescheduler) PrepareEviction(ctx context.Context, action engine.Action) {
+	if !r.steering {
+		return
+	}
+	taint := r.steeringTaint(action.Pod)
+	err := r.updateTaints(ctx, action.Pod.Spec.NodeName, func(node *corev1.Node) bool {
+		if hasTaint(node, taint) {
+			return false
+		}
+		node.Spec.Taints = append(node.Spec.Taints, taint)
+		return true
+	})
+	entry := decisionLog(action.Strategy, action.Group, action.Pod).WithField("taint", taint.Key)
+	if err != nil {
+		entry.Warningf("Failed to taint the source node, the replacement is not steered: %s", err.Error())
+		return
+	}
+	entry.Debug("Tainted the source node to steer the replacement")
+}
+
+// Remove the steering taint of the eviction from the source node: the replacement is scheduled, the wait timed out
+// or the eviction failed. The taint is removed even when the rescheduler shuts down
+func (r *rescheduler) unsteer(action engine.Action) {
+	if !r.steering {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), *cycleDeadline)
+	defer cancel()
+	taint := r.steeringTaint(action.Pod)
+	err := r.updateTaints(ctx, action.Pod.Spec.NodeName, func(node *corev1.Node) bool {
+		return removeTaints(node, func(t corev1.Taint) bool { return t.Key == taint.Key })
+	})
+	if err != nil && !apierrors.IsNotFound(err) {
+		log.WithFields(log.Fields{"node": action.Pod.Spec.NodeName, "taint": taint.Key}).
+			Errorf("Failed to remove the steering taint, it is removed at the next start: %s", err.Error())
+	}
+}
+
+// Remove the steering taints left on the nodes by a previous run of this rescheduler which did not stop cleanly.
+// It is called before the first eviction of every command which evicts Pods: none of the evictions of the identity
+// is in flight then, so every taint of the identity is stale. The taints of the other instances have another identity, they are kept
+func (r *rescheduler) removeStaleSteeringTaints() error {
+	ctx, cancel := r.cycleContext()
+	defer cancel()
+	nodes, err := r.client.ListNodes(ctx)
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		stale := false
+		for _, taint := range node.Spec.Taints {
+			stale = stale || r.ownSteeringTaint(taint)
+		}
+		if !stale {
+			continue
+		}
+		if err := r.updateTaints(ctx, node.Name, func(node *corev1.Node) bool {
+			return removeTaints(node, r.ownSteeringTaint)
+		}); err != nil {
+			return err
+		}
+		log.WithField("node", node.Name).Info("Removed stale steering taints")
+	}
+	return nil
+}
+
+func (r *rescheduler) ownSteeringTaint(taint corev1.Taint) bool {
+	return engine.IsSteeringTaint(taint) && taint.Value == r.steeringID
+}
+
+// Read the node, change its taints and write it back, the update is retried when the node was changed meanwhile.
+// The change returns false when the node does not have to be written
+func (r *rescheduler) updateTaints(ctx context.Context, name string, change func(node *corev1.Node) bool) error {
+	for conflicts := 0; ; conflicts++ {
+		node, err := r.client.GetNode(ctx, name)
+		if err != nil {
+			return err
+		}
+		if !change(node) {
+			return nil
+		}
+		err = r.client.UpdateNode(ctx, node)
+		if err == nil || !apierrors.IsConflict(err) || conflicts >= maxTaintConflicts {
+			return err
+		}
+	}
+}
+
+// Remove the matching taints of the node, false if there is none
+func removeTaints(node *corev1.Node, matches func(corev1.Taint) bool) bool {
+	var kept []corev1.Taint
+	for _, taint := range node.Spec.Taints {
+		if !matches(taint) {
+			kept = append(kept, taint)
+		}
+	}
+	if len(kept) == len(node.Spec.Taints) {
+		return false
+	}
+	node.Spec.Taints = kept
+	return true
+}
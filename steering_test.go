@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/hortonworks/pod-rescheduler/cluster"
+	"github.com/hortonworks/pod-rescheduler/pkg/engine"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func taintedNode(name string, taints ...corev1.Taint) corev1.Node {
+	return corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}, Spec: corev1.NodeSpec{Taints: taints}}
+}
+
+func steeringTaint(uid, id string) corev1.Taint {
+	return corev1.Taint{Key: engine.SteeringTaintPrefix + uid, Value: id, Effect: corev1.TaintEffectPreferNoSchedule}
+}
+
+// The rescheduler of the tests on an in-memory cluster, it steers the replacements with the default identity
+func testRescheduler(nodes []corev1.Node, pods []corev1.Pod) (*rescheduler, *cluster.Memory) {
+	memory := cluster.NewMemory(&cluster.Snapshot{Nodes: nodes, Pods: pods})
+	r := newRescheduler(context.Background(), memory, defaultPolicy(), nil)
+	r.steering, r.steeringID = true, defaultSteeringID
+	return r, memory
+}
+
+func nodeTaints(t *testing.T, c cluster.Client) map[string]string {
+	nodes, err := c.ListNodes(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	taints := make(map[string]string)
+	for _, node := range nodes {
+		taints[node.Name] = fmt.Sprint(node.Spec.Taints)
+	}
+	return taints
+}
+
+func TestRemoveStaleSteeringTaints(t *testing.T) {
+	dedicated := corev1.Taint{Key: "dedicated", Value: defaultSteeringID, Effect: corev1.TaintEffectNoSchedule}
+	other := steeringTaint("uid-2", "other-instance")
+	nodes := []corev1.Node{
+		taintedNode("n0", steeringTaint("uid-0", defaultSteeringID), dedicated, steeringTaint("uid-1", defaultSteeringID)),
+		taintedNode("n1", other),
+		taintedNode("n2"),
+	}
+	cases := []struct {
+		name     string
+		steering bool
+	}{
+		{name: "steering", steering: true},
+		{name: "a previous run steered", steering: false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r, memory := testRescheduler(nodes, nil)
+			r.steering = c.steering
+			if err := r.removeStaleSteeringTaints(); err != nil {
+				t.Fatal(err)
+			}
+			want := map[string]string{
+				"n0": fmt.Sprint([]corev1.Taint{dedicated}),
+				"n1": fmt.Sprint([]corev1.Taint{other}),
+				"n2": fmt.Sprint([]corev1.Taint(nil)),
+			}
+			if got := nodeTaints(t, memory); fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("unexpected taints:\n%v\nwant:\n%v", got, want)
+			}
+		})
+	}
+}
+
+func TestSteeringTaintLastsUntilTheReplacementIsScheduled(t *testing.T) {
+	pod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-0", Namespace: "default", UID: "uid-0"}, Spec: corev1.PodSpec{NodeName: "n0"}}
+	r, memory := testRescheduler([]corev1.Node{taintedNode("n0"), taintedNode("n1")}, []corev1.Pod{pod})
+	action := engine.Action{Type: engine.ActionMove, Strategy: engine.SpreadStrategy, Group: "default/web", Pod: &pod, Target: "n1"}
+
+	r.PrepareEviction(context.Background(), action)
+	r.PrepareEviction(context.Background(), action)
+	if got, want := nodeTaints(t, memory)["n0"], fmt.Sprint([]corev1.Taint{steeringTaint("uid-0", defaultSteeringID)}); got != want {
+		t.Errorf("expected the source node to be tainted once: %s, got: %s", want, got)
+	}
+	r.unsteer(action)
+	if got := nodeTaints(t, memory)["n0"]; got != fmt.Sprint([]corev1.Taint(nil)) {
+		t.Errorf("expected the steering taint to be removed, got: %s", got)
+	}
+}
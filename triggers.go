@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/hortonworks/pod-rescheduler/cluster"
+	"github.com/hortonworks/pod-rescheduler/metrics"
+	"github.com/hortonworks/pod-rescheduler/pkg/engine"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// What triggered a housekeeping cycle
+const (
+	triggerInterval     = "interval"
+	triggerOnce         = "once"
+	triggerNodeAdded    = "node_added"
+	triggerNodeReady    = "node_ready"
+	triggerUncordoned   = "node_uncordoned"
+	triggerTaintRemoved = "taint_removed"
+)
+
+// The longest wait before the node watch is restarted after a failure
+const maxWatchBackoff = 2 * time.Minute
+
+// trigger is the reason of a housekeeping cycle, the node is empty for the periodic cycles
+type trigger struct {
+	reason string
+	node   string
+}
+
+// nodeWatcher turns the node changes which add room for the Pods into housekeeping cycles:
+// a node is added, it becomes Ready, it is uncordoned or a taint is removed from it
+type nodeWatcher struct {
+	client cluster.Client
+	// the last seen version of the nodes, nil until the first list
+	known  map[string]*corev1.Node
+	events chan trigger
+}
+
+// Watch the nodes until the context is cancelled. The node changes within the debounce period after the first one
+// trigger a single cycle and the triggered cycles are at least the minimum interval apart
+func watchNodes(ctx context.Context, client cluster.Client, debounce, minInterval time.Duration) <-chan trigger {
+	w := &nodeWatcher{client: client, events: make(chan trigger, 100)}
+	triggers := make(chan trigger)
+	go w.watch(ctx)
+	go throttle(ctx, w.events, triggers, debounce, minInterval)
+	return triggers
+}
+
+// Restart the watch when it is closed, with an exponential backoff after the failures
+func (w *nodeWatcher) watch(ctx context.Context) {
+	backoff := time.Second
+	for {
+		started := time.Now()
+		err := w.watchOnce(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil || time.Since(started) > maxWatchBackoff {
+			backoff = time.Second
+		}
+		if err == nil {
+			log.Debug("Node watch closed by the API server, restarting it")
+			continue
+		}
+		log.Warningf("Node watch failed, restarting it in %v: %s", backoff, err.Error())
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > maxWatchBackoff {
+			backoff = maxWatchBackoff
+		}
+	}
+}
+
+// List the nodes, so the changes missed while the watch was down are not lost, then watch them until the watch is closed
+func (w *nodeWatcher) watchOnce(ctx context.Context) error {
+	listCtx, cancel := context.WithTimeout(ctx, *cycleDeadline)
+	nodes, err := w.client.ListNodes(listCtx)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("node list error: %s", err.Error())
+	}
+	w.relisted(nodes)
+	watcher, err := w.client.WatchNodes(ctx)
+	if err != nil {
+		return err
+	}
+	defer watcher.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.ResultChan():
+			if !ok {
+				return nil
+			}
+			switch event.Type {
+			case watch.Added, watch.Modified:
+				if node, ok := event.Object.(*corev1.Node); ok {
+					w.update(node)
+				}
+			case watch.Deleted:
+				if node, ok := event.Object.(*corev1.Node); ok {
+					delete(w.known, node.Name)
+				}
+			case watch.Error:
+				return apierrors.FromObject(event.Object)
+			}
+		}
+	}
+}
+
+// The first list only records the nodes, the later ones compare them to the last seen versions
+func (w *nodeWatcher) relisted(nodes []corev1.Node) {
+	if w.known == nil {
+		w.known = make(map[string]*corev1.Node)
+		for i := range nodes {
+			w.known[nodes[i].Name] = &nodes[i]
+		}
+		return
+	}
+	listed := make(map[string]bool)
+	for i := range nodes {
+		listed[nodes[i].Name] = true
+		w.update(&nodes[i])
+	}
+	for name := range w.known {
+		if !listed[name] {
+			delete(w.known, name)
+		}
+	}
+}
+
+func (w *nodeWatcher) update(node *corev1.Node) {
+	reason := nodeChange(w.known[node.Name], node)
+	w.known[node.Name] = node
+	if len(reason) == 0 {
+		return
+	}
+	metrics.NodeEvent(reason)
+	log.WithFields(log.Fields{"node": node.Name, "reason": reason}).Info("Node change triggers a housekeeping cycle")
+	select {
+	case w.events <- trigger{reason: reason, node: node.Name}:
+	default:
+		// a cycle is pending already
+	}
+}
+
+// The change of the node which may allow moving Pods to it, empty if there is none
+func nodeChange(old, node *corev1.Node) string {
+	switch {
+	case old == nil:
+		return triggerNodeAdded
+	case !nodeReady(old) && nodeReady(node):
+		return triggerNodeReady
+	case old.Spec.Unschedulable && !node.Spec.Unschedulable:
+		return triggerUncordoned
+	}
+	for _, taint := range old.Spec.Taints {
+		// the steering taints are removed by the rescheduler itself
+		if !engine.IsSteeringTaint(taint) && !hasTaint(node, taint) {
+			return triggerTaintRemoved
+		}
+	}
+	return ""
+}
+
+func nodeReady(node *corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+func hasTaint(node *corev1.Node, taint corev1.Taint) bool {
+	for _, t := range node.Spec.Taints {
+		if t.Key == taint.Key && t.Effect == taint.Effect {
+			return true
+		}
+	}
+	return false
+}
+
+// Coalesce the events of the debounce period after the first one into a single trigger,
+// which is delayed until the minimum interval has passed since the previous one
+func throttle(ctx context.Context, events <-chan trigger, triggers chan<- trigger, debounce, minInterval time.Duration) {
+	var last time.Time
+	for {
+		var first trigger
+		select {
+		case <-ctx.Done():
+			return
+		case first = <-events:
+		}
+		at := time.Now().Add(debounce)
+		if earliest := last.Add(minInterval); earliest.After(at) {
+			log.Infof("Housekeeping cycle triggered by %s of node %s is delayed until %s", first.reason, first.node, earliest.Format(time.RFC3339))
+			at = earliest
+		}
+		timer := time.NewTimer(time.Until(at))
+	debouncing:
+		for {
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-events:
+			case <-timer.C:
+				break debouncing
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case triggers <- first:
+			last = time.Now()
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	log "github.com/Sirupsen/logrus"
+	"io"
 	"runtime"
 	"sort"
 	"strings"
@@ -17,7 +18,40 @@ const (
 	blue   = 34
 )
 
+// Supported values of the log format
+const (
+	LogFormatText   = "text"
+	LogFormatJSON   = "json"
+	LogFormatLogfmt = "logfmt"
+)
+
+// TimeFormatter prints colored lines on a terminal and key=value pairs otherwise
 type TimeFormatter struct {
+	// DisableColors forces the key=value output, even on a terminal
+	DisableColors bool
+}
+
+// ConfigureLogging sets the formatter and the level of the standard logger
+func ConfigureLogging(out io.Writer, format, level string) error {
+	var formatter log.Formatter
+	switch format {
+	case LogFormatText:
+		formatter = &TimeFormatter{}
+	case LogFormatLogfmt:
+		formatter = &TimeFormatter{DisableColors: true}
+	case LogFormatJSON:
+		formatter = &log.JSONFormatter{}
+	default:
+		return fmt.Errorf("unknown log format: %s, it must be one of: %s, %s, %s", format, LogFormatText, LogFormatJSON, LogFormatLogfmt)
+	}
+	logLevel, err := log.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	log.SetFormatter(formatter)
+	log.SetLevel(logLevel)
+	log.SetOutput(out)
+	return nil
 }
 
 func (f *TimeFormatter) Format(entry *log.Entry) ([]byte, error) {
@@ -32,7 +66,7 @@ func (f *TimeFormatter) Format(entry *log.Entry) ([]byte, error) {
 
 	prefixFieldClashes(entry)
 
-	if log.IsTerminal() {
+	if !f.DisableColors && log.IsTerminal() {
 		printColored(b, entry, keys)
 	} else {
 		f.appendKeyValue(b, "time", entry.Time.Format(time.RFC3339))
@@ -48,13 +48,33 @@ func (s *PodSet) Has(pod *corev1.Pod) bool {
 	return s.HasId(podId(pod))
 }
 
-func (s *PodSet) HasId(pod string) bool {
+// HasId tells whether the set has the Pod with the namespace/name id
+func (s *PodSet) HasId(id string) bool {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
-	_, found := s.set[pod]
+	_, found := s.set[id]
 	return found
 }
 
+// The Pods of different namespaces can have the same name
 func podId(pod *corev1.Pod) string {
-	return pod.Name
+	return pod.Namespace + "/" + pod.Name
+}
+
+// GroupCount returns the number of groups which have a Pod in the set
+func (s *PodSet) GroupCount() int {
+	return len(s.Groups())
+}
+
+// Groups returns the names of the groups which have a Pod in the set
+func (s *PodSet) Groups() map[string]bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	groups := make(map[string]bool)
+	for _, pod := range s.set {
+		if groupName := GetPodGroupName(pod); groupName != nil {
+			groups[*groupName] = true
+		}
+	}
+	return groups
 }
@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPodSetKeysPodsByNamespaceAndName(t *testing.T) {
+	pod := func(namespace, name string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name + "-0", GenerateName: name + "-"}}
+	}
+	s := NewPodSet()
+	s.Add(pod("default", "web"))
+	s.Add(pod("other", "web"))
+	s.Add(pod("other", "db"))
+
+	if !s.HasId("default/web-0") || !s.HasId("other/web-0") || s.HasId("web-0") {
+		t.Errorf("unexpected ids: %v", s.set)
+	}
+	if count := s.GroupCount(); count != 3 {
+		t.Errorf("expected 3 groups, got %d: %v", count, s.Groups())
+	}
+	s.Remove(pod("other", "web"))
+	if !s.Has(pod("default", "web")) || s.HasGroup(pod("other", "web")) {
+		t.Errorf("the Pod of the other namespace was not removed alone: %v", s.set)
+	}
+}
@@ -2,14 +2,15 @@ package utils
 
 import corev1 "k8s.io/api/core/v1"
 
+// GetPodGroupName returns the namespace qualified name of the Deployment/StatefulSet the Pod belongs to
 func GetPodGroupName(pod *corev1.Pod) *string {
-	return getPodGroupName(pod.GenerateName)
+	return getPodGroupName(pod.Namespace, pod.GenerateName)
 }
 
-func getPodGroupName(generateName string) *string {
+func getPodGroupName(namespace, generateName string) *string {
 	if len(generateName) > 0 {
-		generateName = generateName[0 : len(generateName)-1]
-		return &generateName
+		groupName := namespace + "/" + generateName[0:len(generateName)-1]
+		return &groupName
 	}
 	return nil
 }